@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/paulsmith/mcpgo/mcp"
+	"github.com/paulsmith/mcp-go/mcp"
 )
 
 func main() {
@@ -14,32 +14,27 @@ func main() {
 	server := mcp.NewMCPServer("Calculator", "1.0.0")
 
 	// Define the calculator schema
-	calculatorSchema := json.RawMessage(`{
-		"type": "object",
-		"properties": {
-			"operation": {
-				"type": "string",
-				"enum": ["add", "subtract", "multiply", "divide"],
-				"description": "Operation to perform"
-			},
-			"a": {
-				"type": "number",
-				"description": "First operand"
-			},
-			"b": {
-				"type": "number",
-				"description": "Second operand"
-			}
-		},
-		"required": ["operation", "a", "b"]
-	}`)
+	calculatorSchema := mcp.Object().
+		Prop("operation", mcp.String().
+			Enum("add", "subtract", "multiply", "divide").
+			Desc("Operation to perform")).
+		Prop("a", mcp.Number().Desc("First operand")).
+		Prop("b", mcp.Number().Desc("Second operand")).
+		Required("operation", "a", "b").
+		Build()
 
 	// Add a calculator tool
 	server.Tool("calculate", "Perform a calculation", calculatorSchema,
 		func(ctx context.Context, args map[string]interface{}) (string, error) {
 			operation := args["operation"].(string)
-			a := args["a"].(float64)
-			b := args["b"].(float64)
+			a, err := args["a"].(json.Number).Float64()
+			if err != nil {
+				return "", err
+			}
+			b, err := args["b"].(json.Number).Float64()
+			if err != nil {
+				return "", err
+			}
 
 			var result float64
 			switch operation {
@@ -61,13 +56,9 @@ func main() {
 			return fmt.Sprintf("Result: %g", result), nil
 		})
 
-	// Connect to standard I/O
-	if err := server.ConnectStdio(context.Background()); err != nil {
-		fmt.Fprintf(os.Stderr, "Error connecting: %v\n", err)
+	// Serve over standard I/O until the client disconnects
+	if err := server.ServeStdio(context.Background()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error serving: %v\n", err)
 		os.Exit(1)
 	}
-
-	// Run until context is cancelled
-	select {}
 }
-
@@ -0,0 +1,68 @@
+package mcp
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ArgPath returns the value at a dotted path such as "config.timeout" in
+// tool arguments, descending through nested objects. ok is false if any
+// part of the path is missing or is not an object.
+func ArgPath(args map[string]interface{}, path string) (interface{}, bool) {
+	keys := strings.Split(path, ".")
+
+	current := args
+	for i, key := range keys {
+		value, ok := current[key]
+		if !ok {
+			return nil, false
+		}
+		if i == len(keys)-1 {
+			return value, true
+		}
+		current, ok = value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return nil, false
+}
+
+// ArgString returns the string at a dotted path in tool arguments
+func ArgString(args map[string]interface{}, path string) (string, bool) {
+	value, ok := ArgPath(args, path)
+	if !ok {
+		return "", false
+	}
+	s, ok := value.(string)
+	return s, ok
+}
+
+// ArgFloat returns the number at a dotted path in tool arguments
+func ArgFloat(args map[string]interface{}, path string) (float64, bool) {
+	value, ok := ArgPath(args, path)
+	if !ok {
+		return 0, false
+	}
+
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// ArgBool returns the boolean at a dotted path in tool arguments
+func ArgBool(args map[string]interface{}, path string) (bool, bool) {
+	value, ok := ArgPath(args, path)
+	if !ok {
+		return false, false
+	}
+	b, ok := value.(bool)
+	return b, ok
+}
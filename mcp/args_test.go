@@ -0,0 +1,57 @@
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestArgPath(t *testing.T) {
+	args := map[string]interface{}{
+		"name": "job",
+		"config": map[string]interface{}{
+			"timeout": json.Number("2.5"),
+			"retries": float64(3),
+			"verbose": true,
+			"limits":  map[string]interface{}{"cpu": "2"},
+		},
+	}
+
+	if v, ok := ArgPath(args, "config.limits.cpu"); !ok || v != "2" {
+		t.Errorf("config.limits.cpu = %v, %v", v, ok)
+	}
+	if v, ok := ArgPath(args, "config.limits"); !ok || v.(map[string]interface{})["cpu"] != "2" {
+		t.Errorf("config.limits = %v, %v", v, ok)
+	}
+	for _, path := range []string{"missing", "config.missing", "name.length", "config.limits.cpu.x", "", "config."} {
+		if v, ok := ArgPath(args, path); ok {
+			t.Errorf("ArgPath(%q) = %v, want missing", path, v)
+		}
+	}
+
+	if s, ok := ArgString(args, "name"); !ok || s != "job" {
+		t.Errorf("ArgString(name) = %q, %v", s, ok)
+	}
+	if f, ok := ArgFloat(args, "config.timeout"); !ok || f != 2.5 {
+		t.Errorf("ArgFloat(json.Number) = %v, %v", f, ok)
+	}
+	if f, ok := ArgFloat(args, "config.retries"); !ok || f != 3 {
+		t.Errorf("ArgFloat(float64) = %v, %v", f, ok)
+	}
+	if b, ok := ArgBool(args, "config.verbose"); !ok || !b {
+		t.Errorf("ArgBool = %v, %v", b, ok)
+	}
+
+	// Type mismatches report not ok
+	if _, ok := ArgString(args, "config.retries"); ok {
+		t.Error("ArgString accepted a number")
+	}
+	if _, ok := ArgFloat(args, "config.limits.cpu"); ok {
+		t.Error("ArgFloat accepted a string")
+	}
+	if _, ok := ArgFloat(map[string]interface{}{"n": json.Number("x")}, "n"); ok {
+		t.Error("ArgFloat accepted an invalid json.Number")
+	}
+	if _, ok := ArgBool(args, "name"); ok {
+		t.Error("ArgBool accepted a string")
+	}
+}
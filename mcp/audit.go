@@ -0,0 +1,134 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Audit statuses recorded for a tool call
+const (
+	AuditStatusOK        = "ok"
+	AuditStatusError     = "error"
+	AuditStatusCancelled = "cancelled"
+)
+
+// AuditLogger records every tool call for an audit trail: who called which
+// tool with which arguments, how it ended and when it started. identity is
+// the caller's identity from IdentityFromContext, and status is one of the
+// AuditStatus constants. Calls the server rejects before running the tool,
+// such as unknown tools or oversized arguments, are recorded as errors.
+type AuditLogger interface {
+	RecordToolCall(identity, name string, args map[string]interface{}, status string, ts time.Time)
+}
+
+// identityContextKey is the context key for the caller's identity
+type identityContextKey struct{}
+
+// WithIdentity returns a context recording the identity of the caller, for
+// audit logging. Pass it to Connect, or set it in an authenticating
+// transport, so that every request on the connection carries it.
+func WithIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, identityContextKey{}, identity)
+}
+
+// IdentityFromContext returns the caller's identity recorded by
+// WithIdentity, or the empty string if there is none
+func IdentityFromContext(ctx context.Context) string {
+	identity, _ := ctx.Value(identityContextKey{}).(string)
+	return identity
+}
+
+// DefaultRedactedKeys are the argument names whose values JSONLAuditLogger
+// replaces with "***" unless told otherwise with SetRedactedKeys
+var DefaultRedactedKeys = []string{"password", "token", "apiKey", "secret"}
+
+// redactedValue replaces the value of a redacted argument
+const redactedValue = "***"
+
+// JSONLAuditLogger writes audit records as JSON lines, one object per tool
+// call. Arguments named in DefaultRedactedKeys, at any depth, are written as
+// "***" so that secrets stay out of the log. It is safe for concurrent use.
+type JSONLAuditLogger struct {
+	mu       sync.Mutex
+	enc      *json.Encoder
+	redacted map[string]bool // lowercased argument names
+}
+
+// NewJSONLAuditLogger creates an audit logger that appends records to w,
+// typically a file opened with os.O_APPEND
+func NewJSONLAuditLogger(w io.Writer) *JSONLAuditLogger {
+	l := &JSONLAuditLogger{enc: json.NewEncoder(w)}
+	l.SetRedactedKeys(DefaultRedactedKeys...)
+	return l
+}
+
+// SetRedactedKeys replaces the argument names whose values are redacted.
+// Names match case-insensitively. Call it with no keys to log arguments
+// unredacted.
+func (l *JSONLAuditLogger) SetRedactedKeys(keys ...string) {
+	redacted := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		redacted[strings.ToLower(key)] = true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.redacted = redacted
+}
+
+// RecordToolCall writes one audit record. Write errors are dropped.
+func (l *JSONLAuditLogger) RecordToolCall(identity, name string, args map[string]interface{}, status string, ts time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.redacted) > 0 && args != nil {
+		args = redactValue(args, l.redacted).(map[string]interface{})
+	}
+
+	record := struct {
+		Time      time.Time              `json:"time"`
+		Identity  string                 `json:"identity"`
+		Tool      string                 `json:"tool"`
+		Arguments map[string]interface{} `json:"arguments,omitempty"`
+		Status    string                 `json:"status"`
+	}{
+		Time:      ts,
+		Identity:  identity,
+		Tool:      name,
+		Arguments: args,
+		Status:    status,
+	}
+
+	l.enc.Encode(record)
+}
+
+// redactValue returns a copy of v with the values of redacted keys in any
+// nested object replaced. The original is left untouched, as it belongs to
+// the tool call.
+func redactValue(v interface{}, redacted map[string]bool) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			if redacted[strings.ToLower(key)] {
+				copied[key] = redactedValue
+			} else {
+				copied[key] = redactValue(value, redacted)
+			}
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, value := range v {
+			copied[i] = redactValue(value, redacted)
+		}
+		return copied
+	default:
+		return v
+	}
+}
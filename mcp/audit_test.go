@@ -0,0 +1,212 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// auditRecord is a decoded JSONLAuditLogger line
+type auditRecord struct {
+	Identity  string
+	Tool      string
+	Arguments map[string]interface{}
+	Status    string
+}
+
+func decodeAudit(t *testing.T, buf *bytes.Buffer) []auditRecord {
+	t.Helper()
+
+	var records []auditRecord
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var record auditRecord
+		if err := dec.Decode(&record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestAuditLogRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewJSONLAuditLogger(&buf)
+
+	args := map[string]interface{}{
+		"user":     "ann",
+		"Password": "hunter2",
+		"nested":   map[string]interface{}{"apiKey": "k", "region": "eu"},
+		"list":     []interface{}{map[string]interface{}{"token": "t"}},
+	}
+	logger.RecordToolCall("ann", "login", args, AuditStatusOK, time.Now())
+
+	line := buf.String()
+	for _, secret := range []string{"hunter2", `"k"`, `"t"`} {
+		if strings.Contains(line, secret) {
+			t.Errorf("audit log leaks %s: %s", secret, line)
+		}
+	}
+	record := decodeAudit(t, &buf)[0]
+	if record.Arguments["Password"] != "***" || record.Arguments["user"] != "ann" {
+		t.Errorf("arguments logged as %v", record.Arguments)
+	}
+	if nested := record.Arguments["nested"].(map[string]interface{}); nested["apiKey"] != "***" || nested["region"] != "eu" {
+		t.Errorf("nested arguments logged as %v", nested)
+	}
+	if args["Password"] != "hunter2" {
+		t.Error("redaction modified the caller's arguments")
+	}
+
+	logger.SetRedactedKeys("user")
+	logger.RecordToolCall("ann", "login", args, AuditStatusOK, time.Now())
+	record = decodeAudit(t, &buf)[0]
+	if record.Arguments["user"] != "***" || record.Arguments["Password"] != "hunter2" {
+		t.Errorf("with custom keys, arguments logged as %v", record.Arguments)
+	}
+}
+
+func TestAuditLogger(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewServer("test", "1.0", WithAuditLogger(NewJSONLAuditLogger(&buf)))
+	s.AddTool("ok", "", nil, textTool(func(context.Context, map[string]interface{}) string { return "fine" }))
+	s.AddTool("fail", "", nil, func(context.Context, map[string]interface{}) ([]ToolContent, error) {
+		return nil, errTest
+	})
+
+	a, b := NewInMemoryTransportPair()
+	if err := s.Connect(WithIdentity(context.Background(), "alice"), a); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c := &testClient{t: t, server: s, transport: b}
+	c.initialize()
+
+	c.callTool("ok", map[string]interface{}{"n": 1})
+	c.call("tools/call", map[string]interface{}{"name": "fail"})
+
+	records := decodeAudit(t, &buf)
+	if len(records) != 2 {
+		t.Fatalf("got %d audit records, want 2", len(records))
+	}
+	want := []auditRecord{
+		{Identity: "alice", Tool: "ok", Status: AuditStatusOK},
+		{Identity: "alice", Tool: "fail", Status: AuditStatusError},
+	}
+	for i, record := range records {
+		if record.Identity != want[i].Identity || record.Tool != want[i].Tool || record.Status != want[i].Status {
+			t.Errorf("record %d = %+v, want %+v", i, record, want[i])
+		}
+	}
+	if records[0].Arguments["n"] != float64(1) {
+		t.Errorf("arguments logged as %v", records[0].Arguments)
+	}
+}
+
+// fakeAuditLogger records audited calls in memory
+type fakeAuditLogger struct {
+	mu    sync.Mutex
+	calls []auditRecord
+	times []time.Time
+}
+
+func (l *fakeAuditLogger) RecordToolCall(identity, name string, args map[string]interface{}, status string, ts time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.calls = append(l.calls, auditRecord{Identity: identity, Tool: name, Arguments: args, Status: status})
+	l.times = append(l.times, ts)
+}
+
+func TestAuditLoggerSink(t *testing.T) {
+	sink := &fakeAuditLogger{}
+	s := NewServer("test", "1.0", WithAuditLogger(sink), WithRequestTimeout(20*time.Millisecond))
+	s.AddTool("ok", "", nil, textTool(func(context.Context, map[string]interface{}) string { return "fine" }))
+	s.AddTool("slow", "", nil, func(ctx context.Context, _ map[string]interface{}) ([]ToolContent, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	c := newTestClient(t, s)
+
+	before := time.Now()
+	c.callTool("ok", nil)
+	c.callTool("ok", map[string]interface{}{"x": "y"})
+	c.callError("tools/call", map[string]interface{}{"name": "slow"}, -32800)
+	c.callError("tools/call", map[string]interface{}{"name": "missing"}, -32602)
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	want := []string{AuditStatusOK, AuditStatusOK, AuditStatusCancelled, AuditStatusError}
+	if len(sink.calls) != len(want) {
+		t.Fatalf("got %d audit records, want %d", len(sink.calls), len(want))
+	}
+	for i, call := range sink.calls {
+		if call.Status != want[i] || call.Identity != "" {
+			t.Errorf("record %d = %+v", i, call)
+		}
+		if sink.times[i].Before(before) {
+			t.Errorf("record %d timestamp %v precedes the call", i, sink.times[i])
+		}
+	}
+	if sink.calls[1].Arguments["x"] != "y" {
+		t.Errorf("arguments recorded as %v", sink.calls[1].Arguments)
+	}
+}
+
+func TestAuditRejectedCalls(t *testing.T) {
+	sink := &fakeAuditLogger{}
+	s := NewServer("test", "1.0", WithAuditLogger(sink), WithReadOnly(), WithStrictToolResults())
+	readOnly := WithToolAnnotations(ToolAnnotations{ReadOnlyHint: true})
+	fine := textTool(func(context.Context, map[string]interface{}) string { return "fine" })
+	s.AddTool("write", "", nil, fine)
+	s.AddTool("sample", "", nil, fine, readOnly, WithRequiredClientCapabilities("sampling"))
+	s.AddTool("small", "", nil, fine, readOnly, WithMaxArgumentBytes(8))
+	s.AddTool("decoded", "", nil, fine, readOnly, WithArgumentDecoder(func(json.RawMessage) (interface{}, error) {
+		return nil, errTest
+	}))
+	s.AddTool("bogus", "", nil, func(context.Context, map[string]interface{}) ([]ToolContent, error) {
+		return []ToolContent{{Type: "bogus"}}, nil
+	}, readOnly)
+	s.AddTool("empty", "", nil, func(context.Context, map[string]interface{}) ([]ToolContent, error) {
+		return nil, nil
+	}, readOnly)
+	c := newTestClient(t, s)
+
+	calls := []struct {
+		name string
+		args interface{}
+		code int
+	}{
+		{"missing", nil, -32602},
+		{"write", nil, -32601},
+		{"sample", nil, -32601},
+		{"small", map[string]interface{}{"text": "far too long"}, -32602},
+		{"decoded", nil, -32602},
+		{"bogus", nil, -32603},
+		{"empty", nil, -32603},
+	}
+	for _, call := range calls {
+		c.callError("tools/call", map[string]interface{}{"name": call.name, "arguments": call.args}, call.code)
+	}
+	s.Drain()
+	c.callError("tools/call", map[string]interface{}{"name": "empty"}, -32000)
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.calls) != len(calls)+1 {
+		t.Fatalf("got %d audit records, want %d", len(sink.calls), len(calls)+1)
+	}
+	for i, record := range sink.calls {
+		want := "empty"
+		if i < len(calls) {
+			want = calls[i].name
+		}
+		if record.Tool != want || record.Status != AuditStatusError {
+			t.Errorf("record %d = %+v, want an error for %s", i, record, want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package mcp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// toolResultCache holds recent successful results of idempotent tools
+type toolResultCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cachedToolResult
+}
+
+// cachedToolResult is a tool's content and when it stops being served
+type cachedToolResult struct {
+	content []ToolContent
+	expires time.Time
+}
+
+func newToolResultCache(ttl time.Duration) *toolResultCache {
+	return &toolResultCache{
+		ttl:     ttl,
+		entries: make(map[string]cachedToolResult),
+	}
+}
+
+// toolCacheKey identifies a call by tool name and a hash of its arguments.
+// Map keys are marshaled in sorted order, so equal arguments hash the same
+// regardless of the order the client sent them in.
+func toolCacheKey(name string, args map[string]interface{}) (string, bool) {
+	argBytes, err := json.Marshal(args)
+	if err != nil {
+		return "", false
+	}
+
+	sum := sha256.Sum256(argBytes)
+	return name + "\x00" + hex.EncodeToString(sum[:]), true
+}
+
+// get returns the cached content for key if it has not expired
+func (c *toolResultCache) get(key string) ([]ToolContent, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+
+	return entry.content, true
+}
+
+// put caches content under key, dropping any entries that have expired
+func (c *toolResultCache) put(key string, content []ToolContent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	c.entries[key] = cachedToolResult{
+		content: append([]ToolContent(nil), content...),
+		expires: now.Add(c.ttl),
+	}
+}
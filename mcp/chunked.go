@@ -0,0 +1,111 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// DefaultChunkSize is the chunk size NewChunkedTransport uses when given
+// zero or less
+const DefaultChunkSize = 64 << 10
+
+// ResourceChunk is the params of a notifications/resources/chunk
+// notification, which carries one piece of a streamed resource
+type ResourceChunk struct {
+	// RequestID is the ID of the resources/read request being answered
+	RequestID json.RawMessage `json:"requestId"`
+
+	// Index numbers the chunks of a read from zero
+	Index int `json:"index"`
+
+	// Data is the chunk's bytes, base64-encoded on the wire
+	Data []byte `json:"data"`
+}
+
+// ChunkedTransport wraps a transport so that resources registered with
+// AddResourceStream are delivered in chunks rather than read into a single
+// message. Each chunk is sent as a notifications/resources/chunk
+// notification, in order, followed by the resources/read response. The
+// response's content has no text or blob, and its _meta.chunks gives the
+// number of chunks, so the client can check it has them all and join their
+// data. Chunks go through the server's send hook and counters like any other
+// message, and wait for room under WithSendLimit rather than being dropped.
+// Every other message passes through unchanged.
+//
+// Only use it with clients that understand this framing.
+type ChunkedTransport struct {
+	Transport
+	chunkSize int
+}
+
+// chunkedReadResult is the resources/read response that ends a chunked read
+type chunkedReadResult struct {
+	Contents []ResourceContent `json:"contents"`
+	Meta     chunkedReadMeta   `json:"_meta"`
+}
+
+// chunkedReadMeta reports how many chunks a read was split into
+type chunkedReadMeta struct {
+	Chunks int `json:"chunks"`
+}
+
+// NewChunkedTransport wraps transport to stream resources in chunks of at
+// most chunkSize bytes
+func NewChunkedTransport(transport Transport, chunkSize int) *ChunkedTransport {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	return &ChunkedTransport{Transport: transport, chunkSize: chunkSize}
+}
+
+// Kind returns the kind of the wrapped transport
+func (t *ChunkedTransport) Kind() string {
+	if k, ok := t.Transport.(interface{ Kind() string }); ok {
+		return k.Kind()
+	}
+	return ""
+}
+
+// StreamResource sends body as a sequence of chunk notifications followed
+// by the response to request id
+func (t *ChunkedTransport) StreamResource(ctx context.Context, id json.RawMessage, content ResourceContent, body io.Reader, send func(context.Context, *Message) error) error {
+	buf := make([]byte, t.chunkSize)
+
+	chunks := 0
+	for {
+		n, err := io.ReadFull(body, buf)
+		if n > 0 {
+			params, merr := json.Marshal(ResourceChunk{RequestID: id, Index: chunks, Data: buf[:n]})
+			if merr != nil {
+				return merr
+			}
+			if serr := send(ctx, &Message{JSONRPC: "2.0", Method: "notifications/resources/chunk", Params: params}); serr != nil {
+				return serr
+			}
+			chunks++
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			send(ctx, &Message{
+				ID:      id,
+				JSONRPC: "2.0",
+				Error:   &ErrorMessage{Code: -32603, Message: fmt.Sprintf("Error reading resource: %v", err)},
+			})
+			return err
+		}
+	}
+
+	result, err := json.Marshal(chunkedReadResult{
+		Contents: []ResourceContent{content},
+		Meta:     chunkedReadMeta{Chunks: chunks},
+	})
+	if err != nil {
+		return err
+	}
+
+	return send(ctx, &Message{ID: id, JSONRPC: "2.0", Result: result})
+}
@@ -0,0 +1,28 @@
+package mcp
+
+import "encoding/json"
+
+// Codec serializes messages for transports that carry them as bytes. It lets
+// an embedded client and server swap JSON for a binary format such as
+// MessagePack or CBOR while keeping the Message type.
+//
+// StdioTransport always uses JSON, since the MCP stdio framing is
+// newline-delimited JSON.
+type Codec interface {
+	Marshal(msg *Message) ([]byte, error)
+	Unmarshal(data []byte, msg *Message) error
+}
+
+// JSONCodec is the default Codec
+var JSONCodec Codec = jsonCodec{}
+
+// jsonCodec encodes messages as JSON
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(msg *Message) ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+func (jsonCodec) Unmarshal(data []byte, msg *Message) error {
+	return json.Unmarshal(data, msg)
+}
@@ -0,0 +1,56 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"sync/atomic"
+	"testing"
+)
+
+// gobCodec is a binary Codec standing in for MessagePack or CBOR
+type gobCodec struct {
+	encoded atomic.Int32
+}
+
+func (c *gobCodec) Marshal(msg *Message) ([]byte, error) {
+	c.encoded.Add(1)
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(msg)
+	return buf.Bytes(), err
+}
+
+func (c *gobCodec) Unmarshal(data []byte, msg *Message) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(msg)
+}
+
+func TestBinaryCodec(t *testing.T) {
+	codec := &gobCodec{}
+	a, b := NewInMemoryTransportPairWithCodec(codec)
+
+	s := NewServer("test", "1.0")
+	s.AddTool("echo", "", nil, textTool(func(_ context.Context, args map[string]interface{}) string {
+		return args["text"].(string)
+	}))
+	if err := s.Connect(context.Background(), a); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	c := &testClient{t: t, server: s, transport: b}
+	c.initialize()
+
+	text := "héllo <wörld> \x00 \"quoted\""
+	if got := c.callTool("echo", map[string]interface{}{"text": text}).text(); got != text {
+		t.Errorf("echo = %q, want %q", got, text)
+	}
+
+	resp := c.call("no/such/method", nil)
+	if resp.Error == nil || resp.Error.Code != -32601 {
+		t.Errorf("error response = %+v", resp.Error)
+	}
+
+	if codec.encoded.Load() == 0 {
+		t.Error("codec not used")
+	}
+}
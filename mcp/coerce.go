@@ -0,0 +1,151 @@
+package mcp
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// coerceArguments converts top-level arguments whose JSON type differs from
+// the type declared in the tool's input schema, for clients that send "5"
+// where a number is expected. Strings are converted to numbers, integers and
+// booleans, and numbers and booleans to strings. Values that cannot be
+// converted are left alone.
+func coerceArguments(schema json.RawMessage, args map[string]interface{}) {
+	var s struct {
+		Properties map[string]struct {
+			Type interface{} `json:"type"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return
+	}
+
+	for name, value := range args {
+		prop, ok := s.Properties[name]
+		if !ok {
+			continue
+		}
+		types := schemaTypes(prop.Type)
+
+		// A value that already has one of the declared types is valid
+		// input, even if another declared type could also hold it
+		if slices.ContainsFunc(types, func(typ string) bool { return hasSchemaType(value, typ) }) {
+			continue
+		}
+		for _, typ := range types {
+			if coerced, ok := coerceValue(value, typ); ok {
+				args[name] = coerced
+				break
+			}
+		}
+	}
+}
+
+// schemaTypes returns the types named by a JSON Schema type keyword, which
+// may be a single string or a list
+func schemaTypes(t interface{}) []string {
+	switch t := t.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	default:
+		return nil
+	}
+}
+
+// hasSchemaType reports whether value, as decoded with UseNumber, is of the
+// JSON Schema type typ
+func hasSchemaType(value interface{}, typ string) bool {
+	switch v := value.(type) {
+	case json.Number:
+		if typ == "integer" {
+			_, err := v.Int64()
+			return err == nil
+		}
+		return typ == "number"
+	case string:
+		return typ == "string"
+	case bool:
+		return typ == "boolean"
+	case nil:
+		return typ == "null"
+	case map[string]interface{}:
+		return typ == "object"
+	case []interface{}:
+		return typ == "array"
+	default:
+		return false
+	}
+}
+
+// coerceValue converts value to the JSON Schema type typ, reporting whether
+// the result is of that type
+func coerceValue(value interface{}, typ string) (interface{}, bool) {
+	switch typ {
+	case "number":
+		switch v := value.(type) {
+		case json.Number:
+			return v, true
+		case string:
+			if isJSONNumber(v) {
+				return json.Number(v), true
+			}
+		}
+	case "integer":
+		switch v := value.(type) {
+		case json.Number:
+			if _, err := v.Int64(); err == nil {
+				return v, true
+			}
+		case string:
+			if _, err := strconv.ParseInt(v, 10, 64); err == nil && isJSONNumber(v) {
+				return json.Number(v), true
+			}
+		}
+	case "boolean":
+		switch v := value.(type) {
+		case bool:
+			return v, true
+		case string:
+			switch v {
+			case "true":
+				return true, true
+			case "false":
+				return false, true
+			}
+		}
+	case "string":
+		switch v := value.(type) {
+		case string:
+			return v, true
+		case json.Number:
+			return v.String(), true
+		case bool:
+			return strconv.FormatBool(v), true
+		}
+	}
+
+	return value, false
+}
+
+// isJSONNumber reports whether s is a number in JSON's grammar. strconv
+// also accepts forms such as "+5", ".5", "5." and "NaN", which would make
+// the arguments unmarshalable once stored as a json.Number.
+func isJSONNumber(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s {
+		return false
+	}
+	if c := s[0]; c != '-' && (c < '0' || c > '9') {
+		return false
+	}
+	return json.Valid([]byte(s))
+}
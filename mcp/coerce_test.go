@@ -0,0 +1,95 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestArgumentCoercion(t *testing.T) {
+	s := NewServer("test", "1.0", WithArgumentCoercion())
+	schema := json.RawMessage(`{"type":"object","properties":{"n":{"type":"number"},"b":{"type":"boolean"},"s":{"type":"string"}}}`)
+	s.AddTool("types", "", schema, textTool(func(_ context.Context, args map[string]interface{}) string {
+		return fmt.Sprintf("%T %v, %T %v, %T %v", args["n"], args["n"], args["b"], args["b"], args["s"], args["s"])
+	}))
+	c := newTestClient(t, s)
+
+	got := c.callTool("types", map[string]interface{}{"n": "5", "b": "true", "s": 7}).text()
+	if want := "json.Number 5, bool true, string 7"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCoerceValue(t *testing.T) {
+	tests := []struct {
+		value  interface{}
+		typ    string
+		want   interface{}
+		wantOK bool
+	}{
+		{"5", "number", json.Number("5"), true},
+		{"-2.5e3", "number", json.Number("-2.5e3"), true},
+		{"42", "integer", json.Number("42"), true},
+		{"true", "boolean", true, true},
+		{"false", "boolean", false, true},
+		{json.Number("3"), "string", "3", true},
+		{false, "string", "false", true},
+
+		// Not numbers in JSON's grammar, though strconv parses them
+		{"+5", "number", "+5", false},
+		{".5", "number", ".5", false},
+		{"5.", "number", "5.", false},
+		{"NaN", "number", "NaN", false},
+		{"Inf", "number", "Inf", false},
+		{" 5", "number", " 5", false},
+		{"+5", "integer", "+5", false},
+		{"2.5", "integer", "2.5", false},
+		{"yes", "boolean", "yes", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := coerceValue(tt.value, tt.typ)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("coerceValue(%#v, %q) = %#v, %v; want %#v, %v", tt.value, tt.typ, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestCoercedArgumentsRemarshal(t *testing.T) {
+	schema := json.RawMessage(`{"properties":{"n":{"type":"number"}}}`)
+	for _, value := range []string{"5", "+5", ".5", "5.", "NaN", "Inf"} {
+		args := map[string]interface{}{"n": value}
+		coerceArguments(schema, args)
+		if _, err := json.Marshal(args); err != nil {
+			t.Errorf("coercing %q: arguments no longer marshal: %v", value, err)
+		}
+	}
+}
+
+func TestCoerceUnionType(t *testing.T) {
+	schema := json.RawMessage(`{"properties":{"v":{"type":["string","number"]},"b":{"type":["integer","boolean"]}}}`)
+	tests := []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		// Values matching any declared type are left alone
+		{"v", json.Number("5"), json.Number("5")},
+		{"v", "five", "five"},
+		{"b", json.Number("2"), json.Number("2")},
+		{"b", true, true},
+		// Others convert to the first declared type that can hold them
+		{"v", true, "true"},
+		{"b", "7", json.Number("7")},
+		{"b", "false", false},
+		{"b", "x", "x"},
+	}
+	for _, tt := range tests {
+		args := map[string]interface{}{tt.name: tt.value}
+		coerceArguments(schema, args)
+		if got := args[tt.name]; got != tt.want {
+			t.Errorf("%s = %#v: coerced to %#v, want %#v", tt.name, tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// Context keys for values the server attaches to handler contexts
+type (
+	serverContextKey        struct{}
+	loggerContextKey        struct{}
+	transportKindContextKey struct{}
+	argumentsContextKey     struct{}
+	acceptContextKey        struct{}
+	sessionContextKey       struct{}
+)
+
+// withServer returns a context carrying the server handling the request
+func withServer(ctx context.Context, s *Server) context.Context {
+	return context.WithValue(ctx, serverContextKey{}, s)
+}
+
+// serverFromContext returns the server handling the request, if any
+func serverFromContext(ctx context.Context) (*Server, bool) {
+	s, ok := ctx.Value(serverContextKey{}).(*Server)
+	return s, ok
+}
+
+// withLoggerName returns a context whose log messages are attributed to name
+func withLoggerName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, name)
+}
+
+// Log sends a logging message notification from within a handler. The
+// message goes to the client of the server handling the current request and
+// is attributed to the request's logger: the tool name for tool calls and
+// the method name otherwise. Messages below the level set by the client are
+// dropped.
+func Log(ctx context.Context, level string, data interface{}) error {
+	s, ok := serverFromContext(ctx)
+	if !ok {
+		return errors.New("mcp: no server in context")
+	}
+
+	logger, _ := ctx.Value(loggerContextKey{}).(string)
+
+	return s.SendLogMessage(ctx, level, data, logger)
+}
+
+// NotifySession sends a notification from within a handler to the client
+// whose request is being handled, and to no other. It is sent on the
+// connection the request arrived on, so a handler still running after the
+// server reconnects cannot notify the new session; it fails with
+// ErrTransportClosed instead. A nil params is omitted.
+func NotifySession(ctx context.Context, method string, params interface{}) error {
+	s, ok := serverFromContext(ctx)
+	if !ok {
+		return errors.New("mcp: no server in context")
+	}
+	sess, ok := ctx.Value(sessionContextKey{}).(*session)
+	if !ok {
+		return errors.New("mcp: no session in context")
+	}
+
+	return s.notifySession(ctx, sess, method, params)
+}
+
+// session is one connection of a server to a client
+type session struct {
+	transport Transport
+	done      <-chan struct{} // closed when the connection ends
+}
+
+// withSession returns a context recording the connection handlers run on
+func withSession(ctx context.Context, sess *session) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, sess)
+}
+
+// withTransportKind returns a context recording the kind of transport t,
+// if it reports one
+func withTransportKind(ctx context.Context, t Transport) context.Context {
+	k, ok := t.(interface{ Kind() string })
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, transportKindContextKey{}, k.Kind())
+}
+
+// TransportKind returns the kind of transport the current request arrived
+// on, such as "stdio", or the empty string if the transport does not report
+// one
+func TransportKind(ctx context.Context) string {
+	kind, _ := ctx.Value(transportKindContextKey{}).(string)
+	return kind
+}
+
+// withDecodedArguments returns a context carrying a tool's decoded arguments
+func withDecodedArguments(ctx context.Context, args interface{}) context.Context {
+	return context.WithValue(ctx, argumentsContextKey{}, args)
+}
+
+// DecodedArguments returns the value produced by the tool's ArgumentDecoder
+// for the current call, or nil if the tool was registered without one
+func DecodedArguments(ctx context.Context) interface{} {
+	return ctx.Value(argumentsContextKey{})
+}
+
+// withAcceptedMIMETypes returns a context carrying the MIME types a client
+// will accept for a resource, most preferred first
+func withAcceptedMIMETypes(ctx context.Context, accept []string) context.Context {
+	return context.WithValue(ctx, acceptContextKey{}, accept)
+}
+
+// AcceptedMIMETypes returns the MIME types the client listed in the accept
+// param of the current resources/read request, most preferred first, or nil
+// if it expressed no preference
+func AcceptedMIMETypes(ctx context.Context) []string {
+	accept, _ := ctx.Value(acceptContextKey{}).([]string)
+	return accept
+}
+
+// NegotiateMIMEType picks the representation of a resource to return from
+// the MIME types a handler can produce. It returns the first type the client
+// accepts, in the client's order of preference, honoring wildcards such as
+// "text/*" and "*/*". If the client expressed no preference or accepts none
+// of them, it returns the first available type, which should be the
+// resource's declared MIME type.
+func NegotiateMIMEType(ctx context.Context, available ...string) string {
+	if len(available) == 0 {
+		return ""
+	}
+
+	for _, accepted := range AcceptedMIMETypes(ctx) {
+		for _, mimeType := range available {
+			if mimeTypeMatches(accepted, mimeType) {
+				return mimeType
+			}
+		}
+	}
+
+	return available[0]
+}
+
+// mimeTypeMatches reports whether mimeType satisfies the accepted pattern
+func mimeTypeMatches(pattern, mimeType string) bool {
+	if pattern == "*/*" || strings.EqualFold(pattern, mimeType) {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(pattern, "/*")
+	return ok && len(mimeType) > len(prefix) && strings.EqualFold(mimeType[:len(prefix)+1], prefix+"/")
+}
+
+// ProtocolVersionFromContext returns the protocol version negotiated with the
+// client of the server handling the current request, or the empty string
+// outside a handler or before initialize
+func ProtocolVersionFromContext(ctx context.Context) string {
+	s, ok := serverFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	return s.NegotiatedVersion()
+}
@@ -0,0 +1,169 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestProtocolVersionFromContext(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []ServerOption
+		requested string
+		want      string
+	}{
+		{"supported", nil, "2024-11-05", "2024-11-05"},
+		{"unsupported", nil, "2099-01-01", "2024-11-05"},
+		// Revisions whose requirements the server does not meet yet
+		{"2025-03-26", nil, "2025-03-26", "2024-11-05"},
+		{"2025-06-18", nil, "2025-06-18", "2024-11-05"},
+		{"pinned", []ServerOption{WithProtocolVersion("2024-11-05")}, "2099-01-01", "2024-11-05"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer("test", "1.0", tt.opts...)
+			s.AddTool("version", "", nil, textTool(func(ctx context.Context, _ map[string]interface{}) string {
+				return ProtocolVersionFromContext(ctx)
+			}))
+
+			c := connect(t, s)
+			var init struct{ ProtocolVersion string }
+			c.result("initialize", initializeParams(tt.requested, nil), &init)
+			c.notify("notifications/initialized", nil)
+
+			if init.ProtocolVersion != tt.want {
+				t.Errorf("initialize answered %q, want %q", init.ProtocolVersion, tt.want)
+			}
+			if got := s.NegotiatedVersion(); got != tt.want {
+				t.Errorf("NegotiatedVersion = %q, want %q", got, tt.want)
+			}
+			if got := c.callTool("version", nil).text(); got != tt.want {
+				t.Errorf("handler saw %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if v := ProtocolVersionFromContext(context.Background()); v != "" {
+		t.Errorf("outside a handler: got %q, want empty", v)
+	}
+}
+
+func TestLog(t *testing.T) {
+	s := NewServer("test", "1.0")
+	s.AddTool("work", "", nil, textTool(func(ctx context.Context, _ map[string]interface{}) string {
+		Log(ctx, LogLevelDebug, "step 1")
+		Log(ctx, LogLevelWarning, "step 2")
+		return "done"
+	}))
+	c := newTestClient(t, s)
+
+	// logs calls the tool and returns the log messages sent before its result
+	logs := func() []LoggingMessageParams {
+		id := c.request("tools/call", map[string]interface{}{"name": "work"})
+		var messages []LoggingMessageParams
+		for {
+			msg := c.receive()
+			if msg.Method == "" && string(msg.ID) == string(id) {
+				return messages
+			}
+			if msg.Method == "notifications/message" {
+				var params LoggingMessageParams
+				if err := json.Unmarshal(msg.Params, &params); err != nil {
+					t.Fatal(err)
+				}
+				messages = append(messages, params)
+			}
+		}
+	}
+
+	if got := logs(); len(got) != 2 || got[0].Data != "step 1" || got[0].Logger != "work" || got[1].Level != LogLevelWarning {
+		t.Errorf("log messages = %+v", got)
+	}
+
+	var empty struct{}
+	c.result("logging/setLevel", map[string]interface{}{"level": LogLevelWarning}, &empty)
+	if got := logs(); len(got) != 1 || got[0].Data != "step 2" {
+		t.Errorf("log messages at warning = %+v", got)
+	}
+
+	if err := Log(context.Background(), LogLevelInfo, "x"); err == nil {
+		t.Error("Log outside a handler succeeded")
+	}
+}
+
+func TestWithProtocolVersion(t *testing.T) {
+	for version := range supportedProtocolVersions {
+		s := NewServer("test", "1.0", WithProtocolVersion(version))
+		var init struct{ ProtocolVersion string }
+		c := connect(t, s)
+		c.result("initialize", initializeParams(ProtocolVersion, nil), &init)
+		if init.ProtocolVersion != version {
+			t.Errorf("pinned to %s, initialize answered %s", version, init.ProtocolVersion)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("WithProtocolVersion accepted an unsupported version")
+		}
+	}()
+	WithProtocolVersion("1999-01-01")
+}
+
+func TestNotifySession(t *testing.T) {
+	entered := make(chan struct{}, 1)
+	release := make(chan struct{})
+	errs := make(chan error, 1)
+
+	s := NewServer("test", "1.0")
+	s.AddTool("act", "", nil, textTool(func(ctx context.Context, _ map[string]interface{}) string {
+		if err := NotifySession(ctx, "notifications/acted", map[string]interface{}{"n": 1}); err != nil {
+			return err.Error()
+		}
+		return "done"
+	}))
+	s.AddTool("wait", "", nil, textTool(func(ctx context.Context, _ map[string]interface{}) string {
+		entered <- struct{}{}
+		<-release
+		errs <- NotifySession(ctx, "notifications/acted", nil)
+		return "done"
+	}))
+
+	// The notification goes to the session that made the request
+	old := newTestClient(t, s)
+	id := old.request("tools/call", map[string]interface{}{"name": "act"})
+	if msg := old.receive(); msg.Method != "notifications/acted" || string(msg.Params) != `{"n":1}` {
+		t.Fatalf("first message = %+v, want notifications/acted", msg)
+	}
+	if got := old.response(id); got.Error != nil {
+		t.Fatalf("tools/call: %s", got.Error.Message)
+	}
+
+	// A handler outliving its session cannot notify the next one
+	old.request("tools/call", map[string]interface{}{"name": "wait"})
+	<-entered
+	old.transport.Close()
+	waitFor(t, "old session to end", func() bool { return s.Err() != nil })
+
+	current := newTestClient(t, s)
+	close(release)
+	if err := <-errs; err != ErrTransportClosed {
+		t.Errorf("NotifySession from the old session = %v, want ErrTransportClosed", err)
+	}
+	pingID := current.request("ping", nil)
+	for {
+		msg := current.receive()
+		if msg.Method == "notifications/acted" {
+			t.Error("the new session received the old session's notification")
+		}
+		if msg.Method == "" && string(msg.ID) == string(pingID) {
+			break
+		}
+	}
+
+	if err := NotifySession(context.Background(), "notifications/acted", nil); err == nil {
+		t.Error("NotifySession outside a handler succeeded")
+	}
+}
@@ -0,0 +1,43 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// DebugEchoTool returns a tool named "debug/echo" that returns its arguments
+// back as JSON text. It is meant for checking that a client can reach the
+// server and should not be exposed in production; WithDebugEcho registers
+// it.
+func DebugEchoTool() (Tool, ToolHandler) {
+	tool := Tool{
+		Name:        "debug/echo",
+		Description: "Echo the arguments back as JSON, for connectivity testing",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"additionalProperties": true
+		}`),
+		Annotations: &ToolAnnotations{
+			Title:        "Echo",
+			ReadOnlyHint: true,
+		},
+	}
+
+	handler := func(ctx context.Context, args map[string]interface{}) ([]ToolContent, error) {
+		if args == nil {
+			args = map[string]interface{}{}
+		}
+
+		data, err := marshalJSON(args)
+		if err != nil {
+			return nil, err
+		}
+
+		return []ToolContent{{
+			Type: "text",
+			Text: string(data),
+		}}, nil
+	}
+
+	return tool, handler
+}
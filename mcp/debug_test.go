@@ -0,0 +1,28 @@
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDebugEcho(t *testing.T) {
+	c := newTestClient(t, NewServer("test", "1.0", WithDebugEcho()))
+
+	args := map[string]interface{}{"msg": "<hello>", "n": 9007199254740993}
+	result := c.callTool("debug/echo", args)
+	if result.IsError || result.text() != `{"msg":"<hello>","n":9007199254740993}` {
+		t.Errorf("echo = %q, isError %v", result.text(), result.IsError)
+	}
+	if got := c.callTool("debug/echo", nil).text(); got != "{}" {
+		t.Errorf("echo without arguments = %q", got)
+	}
+
+	// The tool is off by default
+	var list struct{ Tools []Tool }
+	off := newTestClient(t, NewServer("test", "1.0"))
+	off.result("tools/list", nil, &list)
+	if len(list.Tools) != 0 {
+		t.Errorf("default server lists %+v", list.Tools)
+	}
+	off.callError("tools/call", map[string]interface{}{"name": "debug/echo", "arguments": json.RawMessage(`{}`)}, -32602)
+}
@@ -0,0 +1,61 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// Elicitation actions a client may report
+const (
+	ElicitAccept  = "accept"
+	ElicitDecline = "decline"
+	ElicitCancel  = "cancel"
+)
+
+// ErrElicitationUnsupported is returned by Elicit when the client did not
+// declare the elicitation capability
+var ErrElicitationUnsupported = errors.New("mcp: client does not support elicitation")
+
+// ElicitResult is the user's response to an elicitation request
+type ElicitResult struct {
+	// Action is ElicitAccept, ElicitDecline or ElicitCancel
+	Action string `json:"action"`
+
+	// Content holds the submitted values when Action is ElicitAccept
+	Content map[string]interface{} `json:"content,omitempty"`
+}
+
+// Elicit asks the user, through the client, for structured input matching
+// schema and waits for the response. Handlers use it to ask for a missing
+// parameter mid-call. Elicit fails with ErrElicitationUnsupported if the
+// client did not declare the elicitation capability.
+func (s *Server) Elicit(ctx context.Context, message string, schema json.RawMessage) (ElicitResult, error) {
+	s.mu.RLock()
+	_, supported := s.clientCapabilities["elicitation"]
+	s.mu.RUnlock()
+
+	if !supported {
+		return ElicitResult{}, ErrElicitationUnsupported
+	}
+
+	params := struct {
+		Message         string          `json:"message"`
+		RequestedSchema json.RawMessage `json:"requestedSchema"`
+	}{
+		Message:         message,
+		RequestedSchema: schema,
+	}
+
+	resultBytes, err := s.request(ctx, "elicitation/create", params)
+	if err != nil {
+		return ElicitResult{}, err
+	}
+
+	var result ElicitResult
+	if err := json.Unmarshal(resultBytes, &result); err != nil {
+		return ElicitResult{}, err
+	}
+
+	return result, nil
+}
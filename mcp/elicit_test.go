@@ -0,0 +1,77 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestElicit(t *testing.T) {
+	schema := json.RawMessage(`{"type":"object","properties":{"name":{"type":"string"}}}`)
+
+	s := NewServer("test", "1.0")
+	s.AddTool("greet", "", nil, func(ctx context.Context, _ map[string]interface{}) ([]ToolContent, error) {
+		result, err := s.Elicit(ctx, "What is your name?", schema)
+		if err != nil {
+			return nil, err
+		}
+		if result.Action != ElicitAccept {
+			return []ToolContent{{Type: "text", Text: result.Action}}, nil
+		}
+		return []ToolContent{{Type: "text", Text: "hello " + result.Content["name"].(string)}}, nil
+	})
+	c := connect(t, s)
+	c.initializeWith(map[string]interface{}{"elicitation": map[string]interface{}{}})
+	c.notify("notifications/initialized", nil)
+	waitFor(t, "initialized notification", s.ready.Load)
+
+	// elicit calls the tool and answers its elicitation request with reply
+	elicit := func(reply *Message) *Message {
+		id := c.request("tools/call", map[string]interface{}{"name": "greet"})
+
+		req := c.receive()
+		if req.Method != "elicitation/create" {
+			t.Fatalf("got %s, want elicitation/create", req.Method)
+		}
+		var params struct {
+			Message         string
+			RequestedSchema json.RawMessage
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			t.Fatal(err)
+		}
+		if params.Message != "What is your name?" || string(params.RequestedSchema) != string(schema) {
+			t.Errorf("elicitation params = %s", req.Params)
+		}
+
+		reply.JSONRPC, reply.ID = "2.0", req.ID
+		c.send(reply)
+		return c.response(id)
+	}
+
+	var result toolResult
+	accepted := elicit(&Message{Result: json.RawMessage(`{"action":"accept","content":{"name":"Ada"}}`)})
+	if err := json.Unmarshal(accepted.Result, &result); err != nil || result.text() != "hello Ada" {
+		t.Errorf("accepted: got %s", accepted.Result)
+	}
+
+	declined := elicit(&Message{Result: json.RawMessage(`{"action":"decline"}`)})
+	if err := json.Unmarshal(declined.Result, &result); err != nil || result.text() != ElicitDecline {
+		t.Errorf("declined: got %s", declined.Result)
+	}
+
+	failed := elicit(&Message{Error: &ErrorMessage{Code: -32603, Message: "no UI"}})
+	if err := json.Unmarshal(failed.Result, &result); err != nil || !result.IsError {
+		t.Errorf("client error: got %s", failed.Result)
+	}
+}
+
+func TestElicitUnsupported(t *testing.T) {
+	s := NewServer("test", "1.0")
+	newTestClient(t, s)
+	waitFor(t, "initialized notification", s.ready.Load)
+
+	if _, err := s.Elicit(context.Background(), "name?", nil); err != ErrElicitationUnsupported {
+		t.Errorf("Elicit = %v, want ErrElicitationUnsupported", err)
+	}
+}
@@ -0,0 +1,51 @@
+package mcp
+
+import (
+	"context"
+	"io/fs"
+	"net/url"
+)
+
+// EmbedResources registers every regular file in fsys as a static resource,
+// such as files embedded with //go:embed. Each resource's URI is prefix
+// followed by the file's slash-separated path in fsys, and its MIME type is
+// guessed from the file extension. File contents are read on each request.
+func EmbedResources(server *Server, fsys fs.FS, prefix string) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		mimeType := mimeTypeByExtension(name)
+
+		server.AddResource(prefix+name, name, "", mimeType, func(ctx context.Context, uri *url.URL) (ResourceContent, error) {
+			data, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return ResourceContent{}, fileReadError(name, err)
+			}
+
+			content := ResourceContent{
+				URI:      uri.String(),
+				Name:     name,
+				MIMEType: mimeType,
+			}
+			if isTextMIMEType(mimeType) {
+				content.Text = string(data)
+			} else {
+				content.Blob = data
+			}
+
+			return content, nil
+		}, WithResourceSize(info.Size()))
+
+		return nil
+	})
+}
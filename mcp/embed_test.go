@@ -0,0 +1,47 @@
+package mcp
+
+import (
+	"embed"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+//go:embed testdata/embed
+var embedded embed.FS
+
+func TestEmbedResources(t *testing.T) {
+	fsys, err := fs.Sub(embedded, "testdata/embed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewServer("test", "1.0")
+	if err := EmbedResources(s, fsys, "embed:///"); err != nil {
+		t.Fatal(err)
+	}
+	c := newTestClient(t, s)
+
+	listed := make(map[string]Resource)
+	for _, r := range c.listResources() {
+		listed[r.URI] = r
+	}
+	if len(listed) != 2 {
+		t.Errorf("listed %d resources, want 2", len(listed))
+	}
+
+	hello, ok := listed["embed:///docs/hello.txt"]
+	if !ok || !strings.HasPrefix(hello.MIMEType, "text/plain") || hello.Size != int64(len("hello, embed\n")) {
+		t.Errorf("hello.txt listed as %+v", hello)
+	}
+	if content := c.readResource("embed:///docs/hello.txt"); content.Text != "hello, embed\n" {
+		t.Errorf("hello.txt read as %+v", content)
+	}
+
+	if logo := listed["embed:///logo.png"]; logo.MIMEType != "image/png" {
+		t.Errorf("logo.png listed as %+v", logo)
+	}
+	if content := c.readResource("embed:///logo.png"); string(content.Blob) != "\x89PNG\r\n\x1a\n" || content.Text != "" {
+		t.Errorf("logo.png read as %+v", content)
+	}
+}
@@ -0,0 +1,147 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxFetchBytes caps how much of a response body the fetch tool returns
+const maxFetchBytes = 5 << 20
+
+// HTTPFetchTool returns a tool named "fetch" that retrieves a URL and returns
+// the response body as an embedded resource with the response's content
+// type. Textual bodies are returned as text and everything else as a blob.
+//
+// Only http and https URLs whose host is on the allowlist may be fetched,
+// including after redirects. An allowlist entry is either a host name,
+// which must match exactly, or a wildcard like "*.example.com", which
+// matches any subdomain. An empty allowlist blocks every URL. Requests are
+// bound to the tool call's context; set a Timeout on client for an upper
+// bound. A nil client uses http.DefaultClient.
+func HTTPFetchTool(client *http.Client, allowlist []string) (Tool, ToolHandler) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	allowed := func(u *url.URL) error {
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+		}
+		if !hostAllowed(u.Hostname(), allowlist) {
+			return fmt.Errorf("host %q is not allowed", u.Hostname())
+		}
+		return nil
+	}
+
+	// Copy the client so redirects are checked against the allowlist too
+	guarded := *client
+	checkRedirect := client.CheckRedirect
+	guarded.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := allowed(req.URL); err != nil {
+			return err
+		}
+		if checkRedirect != nil {
+			return checkRedirect(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+
+	tool := Tool{
+		Name:        "fetch",
+		Description: "Fetch a URL and return its contents",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"url": {
+					"type": "string",
+					"description": "URL to fetch"
+				}
+			},
+			"required": ["url"]
+		}`),
+	}
+
+	handler := func(ctx context.Context, args map[string]interface{}) ([]ToolContent, error) {
+		rawURL, ok := args["url"].(string)
+		if !ok {
+			return nil, errors.New("url argument is required")
+		}
+
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		if err := allowed(u); err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := guarded.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return nil, fmt.Errorf("fetching %s: %s", u, resp.Status)
+		}
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchBytes))
+		if err != nil {
+			return nil, err
+		}
+
+		mimeType := resp.Header.Get("Content-Type")
+		if mimeType == "" {
+			mimeType = http.DetectContentType(body)
+		}
+
+		content := &ResourceContent{
+			URI:      u.String(),
+			MIMEType: mimeType,
+		}
+		if isTextMIMEType(mimeType) {
+			content.Text = string(body)
+		} else {
+			content.Blob = body
+		}
+
+		return []ToolContent{{
+			Type:     "resource",
+			Resource: content,
+		}}, nil
+	}
+
+	return tool, handler
+}
+
+// hostAllowed reports whether host matches an entry in the allowlist
+func hostAllowed(host string, allowlist []string) bool {
+	host = strings.ToLower(host)
+	for _, entry := range allowlist {
+		entry = strings.ToLower(entry)
+		if suffix, ok := strings.CutPrefix(entry, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if host == entry {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPFetchTool(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<p>hello</p>"))
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte{0x89, 'P', 'N', 'G'})
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://blocked.example/", http.StatusFound)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tool, fetch := HTTPFetchTool(srv.Client(), []string{"127.0.0.1"})
+	if tool.Name != "fetch" {
+		t.Errorf("tool name = %q", tool.Name)
+	}
+	ctx := context.Background()
+	fetchURL := func(ctx context.Context, url string) (*ResourceContent, error) {
+		content, err := fetch(ctx, map[string]interface{}{"url": url})
+		if err != nil {
+			return nil, err
+		}
+		if len(content) != 1 || content[0].Type != "resource" {
+			t.Fatalf("fetching %s: got %+v", url, content)
+		}
+		return content[0].Resource, nil
+	}
+
+	page, err := fetchURL(ctx, srv.URL+"/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Text != "<p>hello</p>" || page.MIMEType != "text/html; charset=utf-8" {
+		t.Errorf("page = %+v", page)
+	}
+
+	image, err := fetchURL(ctx, srv.URL+"/image")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(image.Blob) != "\x89PNG" || image.Text != "" || image.MIMEType != "image/png" {
+		t.Errorf("image = %+v", image)
+	}
+
+	for _, url := range []string{"http://blocked.example/", "file:///etc/passwd", srv.URL + "/redirect"} {
+		if _, err := fetchURL(ctx, url); err == nil {
+			t.Errorf("fetching %s succeeded, want it blocked", url)
+		}
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := fetchURL(timeoutCtx, srv.URL+"/slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("fetching a slow URL: got %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestHostAllowed(t *testing.T) {
+	allowlist := []string{"example.com", "*.trusted.org"}
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"example.com", true},
+		{"EXAMPLE.com", true},
+		{"api.example.com", false},
+		{"api.trusted.org", true},
+		{"a.b.trusted.org", true},
+		{"trusted.org", false},
+		{"eviltrusted.org", false},
+	}
+	for _, tt := range tests {
+		if got := hostAllowed(tt.host, allowlist); got != tt.want {
+			t.Errorf("hostAllowed(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// FileSystemResources exposes the regular files under root as resources.
+// It registers the resource template file:///{path...}, where path is the
+// file's slash-separated path relative to root, and a lister that enumerates
+// every file under root for resources/list. Paths that would escape root are
+// rejected. MIME types are guessed from file extensions.
+func FileSystemResources(server *Server, root string) error {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", root)
+	}
+
+	template, err := NewResourceTemplate("file:///{path...}", "Files under "+root, "")
+	if err != nil {
+		return err
+	}
+
+	template.Lister = func(ctx context.Context) ([]Resource, error) {
+		var resources []Resource
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			resources = append(resources, Resource{
+				URI:      fileResourceURI(rel),
+				Name:     rel,
+				MIMEType: mimeTypeByExtension(rel),
+				Size:     info.Size(),
+			})
+			return ctx.Err()
+		})
+		return resources, err
+	}
+
+	server.AddResourceTemplate(template, "files", func(ctx context.Context, uri *url.URL, params map[string]string) (ResourceContent, error) {
+		rel, err := url.PathUnescape(params["path"])
+		if err != nil {
+			return ResourceContent{}, err
+		}
+
+		// Refuse anything that is absolute or climbs out of root
+		if !filepath.IsLocal(filepath.FromSlash(rel)) {
+			return ResourceContent{}, fmt.Errorf("invalid path %q", rel)
+		}
+
+		// Symlinks must not lead outside root either
+		resolved, err := filepath.EvalSymlinks(filepath.Join(root, filepath.FromSlash(rel)))
+		if err != nil {
+			return ResourceContent{}, fileReadError(rel, err)
+		}
+		if inside, err := filepath.Rel(root, resolved); err != nil || !filepath.IsLocal(inside) {
+			return ResourceContent{}, fmt.Errorf("invalid path %q", rel)
+		}
+
+		data, err := os.ReadFile(resolved)
+		if err != nil {
+			return ResourceContent{}, fileReadError(rel, err)
+		}
+
+		content := ResourceContent{
+			URI:      uri.String(),
+			Name:     rel,
+			MIMEType: mimeTypeByExtension(rel),
+		}
+		if isTextMIMEType(content.MIMEType) {
+			content.Text = string(data)
+		} else {
+			content.Blob = data
+		}
+
+		return content, nil
+	})
+
+	return nil
+}
+
+// fileReadError reports a file that does not exist as ErrResourceNotFound,
+// so that clients get a resource-not-found error rather than an internal one
+func fileReadError(name string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s: %w", name, ErrResourceNotFound)
+	}
+	return err
+}
+
+// fileResourceURI builds the resource URI for a slash-separated path
+// relative to the served root
+func fileResourceURI(rel string) string {
+	u := url.URL{Scheme: "file", Path: "/" + rel}
+	return u.String()
+}
+
+// mimeTypeByExtension guesses a MIME type from a file name, falling back to
+// application/octet-stream
+func mimeTypeByExtension(name string) string {
+	if mimeType := mime.TypeByExtension(path.Ext(name)); mimeType != "" {
+		return mimeType
+	}
+	return "application/octet-stream"
+}
+
+// isTextMIMEType reports whether content of the given type should be
+// returned as text rather than as a blob
+func isTextMIMEType(mimeType string) bool {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return false
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case mediaType == "application/json", mediaType == "application/xml",
+		mediaType == "application/javascript", mediaType == "image/svg+xml":
+		return true
+	case strings.HasSuffix(mediaType, "+json"), strings.HasSuffix(mediaType, "+xml"):
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,91 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestFileSystemResources(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"a.txt":         "alpha",
+		"sub/b.json":    `{"b":true}`,
+		"sub/c d.txt":   "spaced",
+		"sub/deep/e.md": "# e",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewServer("test", "1.0")
+	if err := FileSystemResources(s, root); err != nil {
+		t.Fatal(err)
+	}
+	c := newTestClient(t, s)
+
+	// The template itself is listed alongside the files
+	var uris []string
+	for _, r := range c.listResources() {
+		if r.URI != "file:///{path...}" {
+			uris = append(uris, r.URI)
+		}
+	}
+	sort.Strings(uris)
+	want := []string{"file:///a.txt", "file:///sub/b.json", "file:///sub/c%20d.txt", "file:///sub/deep/e.md"}
+	if len(uris) != len(want) {
+		t.Fatalf("listed %v, want %v", uris, want)
+	}
+	for i := range want {
+		if uris[i] != want[i] {
+			t.Errorf("listed %v, want %v", uris, want)
+			break
+		}
+	}
+
+	// Every listed URI can be read
+	for _, uri := range uris {
+		content := c.readResource(uri)
+		if content.Text != files[content.Name] {
+			t.Errorf("reading %s: got %q, want %q", uri, content.Text, files[content.Name])
+		}
+	}
+	if content := c.readResource("file:///sub/b.json"); content.MIMEType != "application/json" {
+		t.Errorf("sub/b.json has MIME type %q", content.MIMEType)
+	}
+
+	// Nothing outside root can be read
+	for _, uri := range []string{
+		"file:///../secret.txt",
+		"file:///sub/../../secret.txt",
+		"file:///sub/%2e%2e/%2e%2e/secret.txt",
+		"file:///link.txt",
+	} {
+		resp := c.call("resources/read", map[string]interface{}{"uri": uri})
+		if resp.Error == nil {
+			t.Errorf("reading %s succeeded: %s", uri, resp.Result)
+		}
+	}
+
+	// Files that do not exist, or that disappear after listing, are not found
+	if err := os.Remove(filepath.Join(root, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	for _, uri := range []string{"file:///missing.txt", "file:///a.txt", "file:///sub/missing/x.txt"} {
+		c.callError("resources/read", map[string]interface{}{"uri": uri}, -32002)
+	}
+}
@@ -0,0 +1,284 @@
+package mcp
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrServerRequestOverHTTP is returned by HTTPTransport.Send for requests
+// the server initiates, such as Elicit and Ping, which a plain HTTP client
+// has no way to receive
+var ErrServerRequestOverHTTP = errors.New("mcp: server requests cannot be sent over plain HTTP")
+
+// maxHTTPBodyBytes bounds the size of a POSTed message
+const maxHTTPBodyBytes = 4 << 20
+
+// HTTPTransport implements the Transport interface for plain JSON-RPC over
+// HTTP. It is an http.Handler: each POST carries one message, and for a
+// request the handler waits for the server's response and writes it as the
+// response body. Nothing stays connected between requests.
+//
+// Like a stdio connection, an HTTPTransport carries a single session with a
+// single client. The server initializes once, for the first POSTed
+// initialize request, and answers any later initialize with -32600. Every
+// other POST is handled as part of that session, whoever sends it. To serve
+// independent clients, give each its own Server and HTTPTransport. Closing
+// the transport, which WithIdleTimeout and WithInitializeTimeout also do,
+// ends the session for good: later POSTs get 503 Service Unavailable.
+//
+// Because there is no channel back to the client outside a response,
+// notifications from the server, such as log messages and list changes, are
+// dropped, and server-initiated requests fail with ErrServerRequestOverHTTP.
+type HTTPTransport struct {
+	incoming chan *Message
+
+	gzipThreshold atomic.Int64
+
+	mu      sync.Mutex
+	pending map[string]chan *Message // keyed by request ID
+
+	done   chan struct{}
+	closed sync.Once
+}
+
+// NewHTTPTransport creates a new HTTP transport. Connect it to a server and
+// mount it on an http.ServeMux.
+func NewHTTPTransport() *HTTPTransport {
+	return &HTTPTransport{
+		incoming: make(chan *Message),
+		pending:  make(map[string]chan *Message),
+		done:     make(chan struct{}),
+	}
+}
+
+// SetGzipThreshold makes the transport gzip responses of at least n bytes,
+// such as large resources/read results, for clients that send
+// Accept-Encoding: gzip. Smaller responses, and all responses when n is zero
+// or less, the default, are sent uncompressed.
+func (t *HTTPTransport) SetGzipThreshold(n int) {
+	t.gzipThreshold.Store(int64(n))
+}
+
+// Kind returns "http"
+func (t *HTTPTransport) Kind() string {
+	return "http"
+}
+
+// Send delivers a response to the HTTP request waiting for it. Responses to
+// requests whose client has gone away are dropped, as are notifications.
+func (t *HTTPTransport) Send(ctx context.Context, msg *Message) error {
+	select {
+	case <-t.done:
+		return ErrTransportClosed
+	default:
+	}
+
+	if msg.Method != "" {
+		if msg.ID != nil {
+			return ErrServerRequestOverHTTP
+		}
+		return nil
+	}
+
+	t.mu.Lock()
+	response, ok := t.pending[string(msg.ID)]
+	t.mu.Unlock()
+
+	if ok {
+		// Buffered for exactly one response
+		select {
+		case response <- msg:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Receive waits for and returns the next POSTed message
+func (t *HTTPTransport) Receive(ctx context.Context) (*Message, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-t.done:
+		return nil, ErrTransportClosed
+	case msg := <-t.incoming:
+		return msg, nil
+	}
+}
+
+// Close terminates the transport. Later POSTs get 503 Service Unavailable.
+func (t *HTTPTransport) Close() error {
+	t.closed.Do(func() { close(t.done) })
+	return nil
+}
+
+// ServeHTTP accepts one POSTed JSON-RPC message. Requests are answered with
+// the server's JSON-RPC response; notifications and responses get 202
+// Accepted with no body. If the client disconnects before the response is
+// ready, the request is cancelled as if the client had sent
+// notifications/cancelled.
+func (t *HTTPTransport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxHTTPBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(w, "error reading request body", http.StatusBadRequest)
+		}
+		return
+	}
+
+	msg, err := ParseMessage(body)
+	if err != nil {
+		code, message := -32700, "Parse error"
+		if errors.Is(err, ErrInvalidMessage) {
+			code, message = -32600, "Invalid Request"
+		}
+		writeHTTPMessage(w, http.StatusBadRequest, &Message{
+			ID:      json.RawMessage("null"),
+			JSONRPC: "2.0",
+			Error:   &ErrorMessage{Code: code, Message: message},
+		})
+		return
+	}
+
+	// Notifications and responses need no answer
+	if msg.Method == "" || msg.ID == nil {
+		if t.deliver(r.Context(), msg) {
+			w.WriteHeader(http.StatusAccepted)
+		} else {
+			http.Error(w, "transport closed", http.StatusServiceUnavailable)
+		}
+		return
+	}
+
+	key := string(msg.ID)
+	response := make(chan *Message, 1)
+
+	t.mu.Lock()
+	if _, exists := t.pending[key]; exists {
+		t.mu.Unlock()
+		http.Error(w, "request ID already in use", http.StatusConflict)
+		return
+	}
+	t.pending[key] = response
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, key)
+		t.mu.Unlock()
+	}()
+
+	if !t.deliver(r.Context(), msg) {
+		http.Error(w, "transport closed", http.StatusServiceUnavailable)
+		return
+	}
+
+	select {
+	case <-r.Context().Done():
+		// The client hung up, so stop working on its request
+		t.deliver(context.Background(), cancelledNotification(msg.ID, "client disconnected"))
+	case <-t.done:
+		http.Error(w, "transport closed", http.StatusServiceUnavailable)
+	case resp := <-response:
+		t.writeResponse(w, r, resp)
+	}
+}
+
+// cancelledNotification builds the notifications/cancelled message for the
+// request with the given ID
+func cancelledNotification(id json.RawMessage, reason string) *Message {
+	params, _ := json.Marshal(struct {
+		RequestID json.RawMessage `json:"requestId"`
+		Reason    string          `json:"reason"`
+	}{id, reason})
+
+	return &Message{JSONRPC: "2.0", Method: "notifications/cancelled", Params: params}
+}
+
+// deliver hands a message to Receive and reports whether it was accepted
+func (t *HTTPTransport) deliver(ctx context.Context, msg *Message) bool {
+	select {
+	case t.incoming <- msg:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-t.done:
+		return false
+	}
+}
+
+// writeResponse writes the server's response to r, compressing it if it is
+// large and the client accepts gzip
+func (t *HTTPTransport) writeResponse(w http.ResponseWriter, r *http.Request, msg *Message) {
+	threshold := t.gzipThreshold.Load()
+	if threshold <= 0 {
+		writeHTTPMessage(w, http.StatusOK, msg)
+		return
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		http.Error(w, "error encoding response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("Vary", "Accept-Encoding")
+	if int64(len(body)) < threshold || !acceptsGzip(r) {
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+		return
+	}
+
+	w.Header().Set("Content-Encoding", "gzip")
+	w.WriteHeader(http.StatusOK)
+	gz := gzip.NewWriter(w)
+	gz.Write(body)
+	gz.Close()
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding header allows a
+// gzip-encoded response
+func acceptsGzip(r *http.Request) bool {
+	for _, header := range r.Header.Values("Accept-Encoding") {
+		for _, coding := range strings.Split(header, ",") {
+			name, params, _ := strings.Cut(coding, ";")
+			name = strings.TrimSpace(name)
+			if name != "gzip" && name != "*" {
+				continue
+			}
+			if q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+				if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// writeHTTPMessage writes msg as a JSON response body
+func writeHTTPMessage(w http.ResponseWriter, status int, msg *Message) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(msg)
+}
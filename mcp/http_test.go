@@ -0,0 +1,213 @@
+package mcp
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// postMessage POSTs body to url and returns the status and decoded response
+// message, if any
+func postMessage(t *testing.T, url, body string) (int, *Message) {
+	t.Helper()
+
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Content-Type") != "application/json" {
+		return resp.StatusCode, nil
+	}
+	var msg Message
+	if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, &msg
+}
+
+// newHTTPServer connects s to an HTTPTransport served by an httptest server
+func newHTTPServer(t *testing.T, s *Server) (*HTTPTransport, *httptest.Server) {
+	t.Helper()
+
+	transport := NewHTTPTransport()
+	if err := s.Connect(context.Background(), transport); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	ts := httptest.NewServer(transport)
+	t.Cleanup(ts.Close)
+	return transport, ts
+}
+
+const httpInitialize = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`
+
+func TestHTTPTransport(t *testing.T) {
+	s := NewServer("test", "1.0")
+	s.AddTool("greet", "", nil, textTool(func(_ context.Context, args map[string]interface{}) string {
+		return "hello " + args["name"].(string)
+	}))
+	_, ts := newHTTPServer(t, s)
+
+	if status, msg := postMessage(t, ts.URL, httpInitialize); status != http.StatusOK || msg.Error != nil {
+		t.Fatalf("initialize: %d %+v", status, msg)
+	}
+	if status, _ := postMessage(t, ts.URL, `{"jsonrpc":"2.0","method":"notifications/initialized"}`); status != http.StatusAccepted {
+		t.Errorf("notification: status %d, want 202", status)
+	}
+
+	status, msg := postMessage(t, ts.URL, `{"jsonrpc":"2.0","id":"call-1","method":"tools/call","params":{"name":"greet","arguments":{"name":"web"}}}`)
+	if status != http.StatusOK || string(msg.ID) != `"call-1"` {
+		t.Fatalf("tools/call: %d %+v", status, msg)
+	}
+	var result toolResult
+	if err := json.Unmarshal(msg.Result, &result); err != nil || result.text() != "hello web" {
+		t.Errorf("tools/call result %s", msg.Result)
+	}
+
+	// The session is initialized once, whoever asks again
+	if _, msg := postMessage(t, ts.URL, httpInitialize); msg.Error == nil || msg.Error.Code != -32600 {
+		t.Errorf("second initialize: %+v, want -32600", msg)
+	}
+
+	// Server-initiated requests have nowhere to go
+	if _, err := s.Ping(context.Background()); err != ErrServerRequestOverHTTP {
+		t.Errorf("Ping = %v, want ErrServerRequestOverHTTP", err)
+	}
+}
+
+func TestHTTPTransportRejects(t *testing.T) {
+	transport, ts := newHTTPServer(t, NewServer("test", "1.0"))
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status %d, want 405", resp.StatusCode)
+	}
+
+	if status, msg := postMessage(t, ts.URL, `{"jsonrpc":`); status != http.StatusBadRequest || msg.Error.Code != -32700 {
+		t.Errorf("truncated JSON: %d %+v", status, msg)
+	}
+	if status, msg := postMessage(t, ts.URL, `{"jsonrpc":"1.0","id":1,"method":"ping"}`); status != http.StatusBadRequest || msg.Error.Code != -32600 {
+		t.Errorf("wrong jsonrpc version: %d %+v", status, msg)
+	}
+
+	transport.Close()
+	if status, _ := postMessage(t, ts.URL, `{"jsonrpc":"2.0","id":1,"method":"ping"}`); status != http.StatusServiceUnavailable {
+		t.Errorf("after Close: status %d, want 503", status)
+	}
+}
+
+// postEncoded POSTs body with the given Accept-Encoding and returns the
+// response's Content-Encoding and decoded message
+func postEncoded(t *testing.T, url, body, acceptEncoding string) (string, *Message) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var r io.Reader = resp.Body
+	encoding := resp.Header.Get("Content-Encoding")
+	if encoding == "gzip" {
+		if r, err = gzip.NewReader(resp.Body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var msg Message
+	if err := json.NewDecoder(r).Decode(&msg); err != nil {
+		t.Fatal(err)
+	}
+	return encoding, &msg
+}
+
+func TestHTTPTransportGzip(t *testing.T) {
+	large := strings.Repeat("all work and no play ", 10000)
+
+	s := NewServer("test", "1.0")
+	s.AddResource("text://large", "large", "", "text/plain", textResource(large))
+	transport, ts := newHTTPServer(t, s)
+	transport.SetGzipThreshold(1024)
+	postMessage(t, ts.URL, httpInitialize)
+
+	read := `{"jsonrpc":"2.0","id":2,"method":"resources/read","params":{"uri":"text://large"}}`
+	tests := []struct {
+		body           string
+		acceptEncoding string
+		want           string
+	}{
+		{read, "gzip, deflate", "gzip"},
+		{read, "identity", ""},
+		{read, "gzip;q=0", ""},
+		{`{"jsonrpc":"2.0","id":3,"method":"ping"}`, "gzip", ""},
+	}
+	for _, tt := range tests {
+		encoding, msg := postEncoded(t, ts.URL, tt.body, tt.acceptEncoding)
+		if encoding != tt.want {
+			t.Errorf("Accept-Encoding %q: Content-Encoding %q, want %q", tt.acceptEncoding, encoding, tt.want)
+		}
+		if msg.Error != nil {
+			t.Fatalf("Accept-Encoding %q: %s", tt.acceptEncoding, msg.Error.Message)
+		}
+		if string(msg.ID) == "2" {
+			var result readResult
+			if err := json.Unmarshal(msg.Result, &result); err != nil || result.Contents[0].Text != large {
+				t.Errorf("Accept-Encoding %q: resource content corrupted", tt.acceptEncoding)
+			}
+		}
+	}
+}
+
+func TestHTTPTransportDisconnectCancels(t *testing.T) {
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+
+	s := NewServer("test", "1.0")
+	s.AddTool("slow", "", nil, func(ctx context.Context, _ map[string]interface{}) ([]ToolContent, error) {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	})
+	_, ts := newHTTPServer(t, s)
+	postMessage(t, ts.URL, httpInitialize)
+
+	ctx, hangUp := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ts.URL, strings.NewReader(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"slow"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		<-started
+		hangUp()
+	}()
+	if resp, err := http.DefaultClient.Do(req); err == nil {
+		resp.Body.Close()
+		t.Fatal("request completed despite the client hanging up")
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(testTimeout):
+		t.Fatal("handler not cancelled after the client disconnected")
+	}
+}
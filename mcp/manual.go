@@ -0,0 +1,91 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// errNotManual is returned by ServeOnce and Serve when the server was not
+// connected with ConnectManual
+var errNotManual = errors.New("mcp: server not connected with ConnectManual")
+
+// ConnectManual attaches a transport to the server like Connect, but does not
+// start reading messages. The caller drives the server with ServeOnce or
+// Serve, which makes it possible to process messages deterministically on a
+// single goroutine.
+//
+// Handlers run on the caller's goroutine, so a handler that waits on the
+// client, such as one calling Elicit, blocks forever.
+func (s *Server) ConnectManual(ctx context.Context, transport Transport) error {
+	s.attach(ctx, transport, true)
+	return nil
+}
+
+// ServeOnce reads one message and handles it before returning. It returns
+// the transport's error if the read fails, including io.EOF when the client
+// has closed the connection.
+func (s *Server) ServeOnce(ctx context.Context) error {
+	s.transportMu.RLock()
+	transport, connCtx, manual := s.transport, s.connCtx, s.manual
+	s.transportMu.RUnlock()
+
+	if transport == nil {
+		return ErrNotConnected
+	}
+	if !manual {
+		return errNotManual
+	}
+
+	msg, err := transport.Receive(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.resetIdleTimer()
+	s.recordReceived(msg)
+
+	// Responses answer requests the server sent
+	if msg.Method == "" && msg.ID != nil {
+		s.handleResponse(msg)
+		return nil
+	}
+
+	s.beginRequest()
+	defer s.endRequest()
+
+	s.handleMessage(connCtx, msg)
+	return nil
+}
+
+// Serve handles messages one at a time on the caller's goroutine until the
+// context is cancelled or the connection closes, and returns the reason, as
+// Err does. Done is closed when it returns. Serve may be called once per
+// ConnectManual.
+func (s *Server) Serve(ctx context.Context) error {
+	s.transportMu.RLock()
+	cancel, done, manual := s.connCancel, s.done, s.manual
+	s.transportMu.RUnlock()
+
+	if done == nil {
+		return ErrNotConnected
+	}
+	if !manual {
+		return errNotManual
+	}
+
+	defer close(done)
+	defer s.disconnected()
+	defer cancel()
+
+	for {
+		err := s.ServeOnce(ctx)
+		if err != nil && !isMessageError(err) {
+			s.setLoopErr(err)
+			return err
+		}
+		if err != nil {
+			s.reportError(ctx, fmt.Errorf("reading message: %w", err))
+		}
+	}
+}
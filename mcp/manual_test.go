@@ -0,0 +1,72 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"testing"
+)
+
+func TestServeOnce(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer("test", "1.0")
+	if err := s.ServeOnce(ctx); err != ErrNotConnected {
+		t.Errorf("ServeOnce before connecting = %v, want ErrNotConnected", err)
+	}
+
+	var calls int
+	s.AddTool("count", "", nil, textTool(func(context.Context, map[string]interface{}) string {
+		calls++
+		return "counted"
+	}))
+
+	a, b := NewInMemoryTransportPair()
+	if err := s.ConnectManual(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c := &testClient{t: t, server: s, transport: b}
+
+	// Nothing is handled until the caller pumps a message
+	c.request("initialize", initializeParams(ProtocolVersion, nil))
+	c.notify("notifications/initialized", nil)
+	toolID := c.request("tools/call", map[string]interface{}{"name": "count"})
+	if s.initialized.Load() {
+		t.Fatal("initialize handled before ServeOnce")
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := s.ServeOnce(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if msg := c.receive(); msg.Error != nil || !s.ready.Load() {
+		t.Fatalf("after the handshake: %+v, ready %v", msg, s.ready.Load())
+	}
+	if calls != 0 {
+		t.Fatal("tool called before its message was pumped")
+	}
+
+	if err := s.ServeOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("tool called %d times, want 1", calls)
+	}
+	if msg := c.receive(); string(msg.ID) != string(toolID) {
+		t.Errorf("got %s, want the tool result", msg.ID)
+	}
+
+	b.Close()
+	if err := s.ServeOnce(ctx); err != io.EOF {
+		t.Errorf("ServeOnce after the client closed = %v, want io.EOF", err)
+	}
+}
+
+func TestServeOnceRequiresManual(t *testing.T) {
+	s := NewServer("test", "1.0")
+	connect(t, s)
+
+	if err := s.ServeOnce(context.Background()); err != errNotManual {
+		t.Errorf("ServeOnce after Connect = %v, want errNotManual", err)
+	}
+}
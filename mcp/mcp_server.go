@@ -3,6 +3,8 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/url"
 )
 
@@ -12,12 +14,18 @@ type MCPServer struct {
 }
 
 // NewMCPServer creates a new MCP server
-func NewMCPServer(name, version string) *MCPServer {
+func NewMCPServer(name, version string, opts ...ServerOption) *MCPServer {
 	return &MCPServer{
-		server: NewServer(name, version),
+		server: NewServer(name, version, opts...),
 	}
 }
 
+// Server returns the underlying Server, for lower-level APIs such as custom
+// method handlers and change notifications
+func (s *MCPServer) Server() *Server {
+	return s.server
+}
+
 // Resource adds a static resource to the server
 func (s *MCPServer) Resource(name, uri, description, mimeType string, handler func(ctx context.Context) (string, error)) {
 	s.server.AddResource(uri, name, description, mimeType, func(ctx context.Context, uri *url.URL) (ResourceContent, error) {
@@ -28,6 +36,7 @@ func (s *MCPServer) Resource(name, uri, description, mimeType string, handler fu
 
 		return ResourceContent{
 			URI:      uri.String(),
+			Name:     name,
 			Text:     text,
 			MIMEType: mimeType,
 		}, nil
@@ -51,6 +60,7 @@ func (s *MCPServer) ResourceTemplate(name, uriTemplate, description, mimeType st
 
 		return ResourceContent{
 			URI:      uri.String(),
+			Name:     name,
 			Text:     text,
 			MIMEType: mimeType,
 		}, nil
@@ -58,7 +68,7 @@ func (s *MCPServer) ResourceTemplate(name, uriTemplate, description, mimeType st
 }
 
 // Tool adds a tool to the server
-func (s *MCPServer) Tool(name, description string, schema json.RawMessage, handler func(ctx context.Context, args map[string]interface{}) (string, error)) {
+func (s *MCPServer) Tool(name, description string, schema json.RawMessage, handler func(ctx context.Context, args map[string]interface{}) (string, error), opts ...ToolOption) {
 	s.server.AddTool(name, description, schema, func(ctx context.Context, args map[string]interface{}) ([]ToolContent, error) {
 		text, err := handler(ctx, args)
 		if err != nil {
@@ -69,7 +79,7 @@ func (s *MCPServer) Tool(name, description string, schema json.RawMessage, handl
 			Type: "text",
 			Text: text,
 		}}, nil
-	})
+	}, opts...)
 }
 
 // Prompt adds a prompt template to the server
@@ -77,11 +87,64 @@ func (s *MCPServer) Prompt(name, description string, arguments []PromptArgument,
 	s.server.AddPrompt(name, description, arguments, handler)
 }
 
+// OnToolCall sets a hook that is called after every tool call
+func (s *MCPServer) OnToolCall(hook ToolCallHook) {
+	s.server.OnToolCall(hook)
+}
+
 // ConnectStdio connects the server using standard I/O
 func (s *MCPServer) ConnectStdio(ctx context.Context) error {
 	return s.server.Connect(ctx, NewStdioTransport())
 }
 
+// ServeStdio connects the server using standard I/O and blocks until the
+// context is cancelled or the client closes the connection
+func (s *MCPServer) ServeStdio(ctx context.Context) error {
+	return s.Serve(ctx, NewStdioTransport())
+}
+
+// Serve connects the server using the given transport and blocks until the
+// context is cancelled or the connection closes. It returns the context's
+// error if the context was cancelled, the transport's error if reading
+// failed, and nil if the client closed the connection.
+func (s *MCPServer) Serve(ctx context.Context, transport Transport) error {
+	if err := s.server.Connect(ctx, transport); err != nil {
+		return err
+	}
+
+	<-s.server.Done()
+	loopErr := s.server.Err()
+
+	closeErr := s.server.Close()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	// A closed connection or a Close call ends serving normally; anything
+	// else broke the transport
+	if loopErr != nil && !errors.Is(loopErr, io.EOF) && !errors.Is(loopErr, ErrTransportClosed) && !errors.Is(loopErr, context.Canceled) {
+		return loopErr
+	}
+
+	return closeErr
+}
+
+// Elicit asks the user for structured input matching schema
+func (s *MCPServer) Elicit(ctx context.Context, message string, schema json.RawMessage) (ElicitResult, error) {
+	return s.server.Elicit(ctx, message, schema)
+}
+
+// Done returns a channel that is closed when the server stops reading
+// messages
+func (s *MCPServer) Done() <-chan struct{} {
+	return s.server.Done()
+}
+
+// Err reports why the server stopped reading messages
+func (s *MCPServer) Err() error {
+	return s.server.Err()
+}
+
 // Close terminates the server
 func (s *MCPServer) Close() error {
 	return s.server.Close()
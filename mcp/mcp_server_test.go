@@ -0,0 +1,88 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// serveAsync runs s.Serve on transport and returns a channel with its result
+func serveAsync(ctx context.Context, s *MCPServer, transport Transport) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, transport) }()
+	return done
+}
+
+func TestServeReturnsOnEOF(t *testing.T) {
+	s := NewMCPServer("test", "1.0")
+	a, b := NewInMemoryTransportPair()
+	done := serveAsync(context.Background(), s, a)
+
+	b.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve = %v, want nil", err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("Serve did not return after EOF")
+	}
+}
+
+func TestServeReturnsOnCancel(t *testing.T) {
+	s := NewMCPServer("test", "1.0")
+	a, _ := NewInMemoryTransportPair()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := serveAsync(ctx, s, a)
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("Serve = %v, want context.Canceled", err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("Serve did not return after cancellation")
+	}
+}
+
+func TestServeReturnsReadError(t *testing.T) {
+	readErr := errors.New("disk on fire")
+	s := NewMCPServer("test", "1.0")
+	done := serveAsync(context.Background(), s, NewStdioTransportWithIO(failingReader{readErr}, io.Discard))
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, readErr) {
+			t.Errorf("Serve = %v, want %v", err, readErr)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("Serve did not return after a read error")
+	}
+}
+
+func TestMCPServerServer(t *testing.T) {
+	s := NewMCPServer("test", "1.0")
+	s.Tool("hello", "", nil, func(context.Context, map[string]interface{}) (string, error) {
+		return "hi", nil
+	})
+
+	// The low-level server sees what the high-level API registered
+	low := s.Server()
+	if tools := low.ToolsByAnnotation(false); len(tools) != 1 || tools[0].Name != "hello" {
+		t.Errorf("low-level tools = %+v", tools)
+	}
+
+	c := newTestClient(t, low)
+	if err := low.NotifyToolsChanged(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if msg := c.receive(); msg.Method != "notifications/tools/list_changed" {
+		t.Errorf("got %s, want tools/list_changed", msg.Method)
+	}
+	if got := c.callTool("hello", nil).text(); got != "hi" {
+		t.Errorf("hello = %q", got)
+	}
+}
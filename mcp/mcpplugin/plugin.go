@@ -0,0 +1,67 @@
+// Package mcpplugin loads MCP tools, resources and prompts from Go plugins.
+//
+// Go plugins only work on Linux, FreeBSD and macOS, and only in binaries
+// built with cgo enabled. A plugin must be built with the same Go version,
+// the same build flags and the same versions of every package it shares with
+// the server, including this module, or it will fail to open. Plugins cannot
+// be unloaded. This package is separate from mcp because importing the
+// plugin package makes every binary that links it larger.
+package mcpplugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"plugin"
+
+	"github.com/paulsmith/mcp-go/mcp"
+)
+
+// RegisterSymbol is the name of the function each plugin must export. Its
+// signature is func(server *mcp.MCPServer).
+const RegisterSymbol = "RegisterMCP"
+
+// LoadPlugins opens every .so file in dir, in lexical order, and calls its
+// exported RegisterMCP function with server so that it can register tools,
+// resources and prompts. Call it before connecting the server. It stops at
+// the first plugin that fails to open or lacks a valid RegisterMCP.
+func LoadPlugins(server *mcp.MCPServer, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".so" {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		if err := loadPlugin(server, path); err != nil {
+			return fmt.Errorf("loading plugin %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+// loadPlugin opens the plugin at path and calls its RegisterMCP function
+func loadPlugin(server *mcp.MCPServer, path string) error {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return err
+	}
+
+	sym, err := p.Lookup(RegisterSymbol)
+	if err != nil {
+		return err
+	}
+
+	register, ok := sym.(func(*mcp.MCPServer))
+	if !ok {
+		return fmt.Errorf("%s has type %T, want func(*mcp.MCPServer)", RegisterSymbol, sym)
+	}
+
+	register(server)
+	return nil
+}
@@ -0,0 +1,117 @@
+//go:build plugintest
+
+// These tests compile a sample plugin, which needs cgo and takes a while, so
+// they only run with go test -tags plugintest
+
+package mcpplugin_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"testing"
+	"time"
+
+	"github.com/paulsmith/mcp-go/mcp"
+	"github.com/paulsmith/mcp-go/mcp/mcpplugin"
+)
+
+// buildPlugin compiles the plugin in testdata/name into dir with the build
+// flags of the test binary, which the plugin must match to load
+func buildPlugin(t *testing.T, dir, name string) {
+	t.Helper()
+
+	args := []string{"build", "-buildmode=plugin", "-tags=plugintest"}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "-race" && setting.Value == "true" {
+				args = append(args, "-race")
+			}
+		}
+	}
+	args = append(args, "-o", filepath.Join(dir, name+".so"), "./testdata/"+name)
+
+	out, err := exec.Command("go", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("building plugin %s: %v\n%s", name, err, out)
+	}
+}
+
+func TestLoadPlugins(t *testing.T) {
+	dir := t.TempDir()
+	buildPlugin(t, dir, "greet")
+	// Files without the .so extension are ignored
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("not a plugin"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := mcp.NewMCPServer("test", "1.0")
+	if err := mcpplugin.LoadPlugins(server, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := mcp.NewInMemoryTransportPair()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Server().Connect(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	call := func(id, method string, params interface{}) json.RawMessage {
+		t.Helper()
+
+		raw, err := json.Marshal(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Send(ctx, &mcp.Message{JSONRPC: "2.0", ID: json.RawMessage(id), Method: method, Params: raw}); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := b.Receive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Error != nil {
+			t.Fatalf("%s: %s", method, resp.Error.Message)
+		}
+		return resp.Result
+	}
+
+	call("1", "initialize", map[string]interface{}{
+		"protocolVersion": mcp.ProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]interface{}{"name": "test", "version": "1.0"},
+	})
+	if err := b.Send(ctx, &mcp.Message{JSONRPC: "2.0", Method: "notifications/initialized"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var result struct {
+		Content []mcp.ToolContent `json:"content"`
+	}
+	raw := call("2", "tools/call", map[string]interface{}{"name": "greet", "arguments": map[string]interface{}{"name": "plugin"}})
+	if err := json.Unmarshal(raw, &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Content) != 1 || result.Content[0].Text != "Hello, plugin!" {
+		t.Errorf("greet result = %s", raw)
+	}
+}
+
+func TestLoadPluginsInvalid(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "broken.so"), []byte("not a shared object"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mcpplugin.LoadPlugins(mcp.NewMCPServer("test", "1.0"), dir); err == nil {
+		t.Error("LoadPlugins succeeded with an invalid plugin")
+	}
+	if err := mcpplugin.LoadPlugins(mcp.NewMCPServer("test", "1.0"), filepath.Join(dir, "missing")); err == nil {
+		t.Error("LoadPlugins succeeded with a missing directory")
+	}
+}
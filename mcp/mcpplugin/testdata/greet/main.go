@@ -0,0 +1,17 @@
+// Command greet is a sample plugin for the mcpplugin tests. Build it with
+// go build -buildmode=plugin.
+package main
+
+import (
+	"context"
+
+	"github.com/paulsmith/mcp-go/mcp"
+)
+
+// RegisterMCP registers a greet tool
+func RegisterMCP(server *mcp.MCPServer) {
+	server.Tool("greet", "Greets someone by name", nil, func(_ context.Context, args map[string]interface{}) (string, error) {
+		name, _ := args["name"].(string)
+		return "Hello, " + name + "!", nil
+	})
+}
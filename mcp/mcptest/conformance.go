@@ -0,0 +1,197 @@
+// Package mcptest provides utilities for testing MCP components.
+package mcptest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/paulsmith/mcp-go/mcp"
+)
+
+// conformanceTimeout bounds every blocking step so that a broken transport
+// fails the test instead of hanging it
+const conformanceTimeout = 5 * time.Second
+
+// RunTransportConformance runs a suite of behavioral checks against a
+// Transport implementation. The factory must return two freshly connected
+// transports: messages sent on a are received on b, and vice versa. It is
+// called once per subtest.
+//
+// The suite checks that:
+//   - messages round-trip in both directions
+//   - messages are received in the order they were sent
+//   - Close unblocks a pending Receive
+//   - Receive returns when its context is cancelled
+func RunTransportConformance(t *testing.T, factory func() (a, b mcp.Transport)) {
+	t.Helper()
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		a, b := factory()
+		defer a.Close()
+		defer b.Close()
+
+		testRoundTrip(t, a, b)
+		testRoundTrip(t, b, a)
+	})
+
+	t.Run("Ordering", func(t *testing.T) {
+		a, b := factory()
+		defer a.Close()
+		defer b.Close()
+
+		testOrdering(t, a, b)
+	})
+
+	t.Run("CloseUnblocksReceive", func(t *testing.T) {
+		a, b := factory()
+		defer b.Close()
+
+		errc := make(chan error, 1)
+		go func() {
+			_, err := a.Receive(context.Background())
+			errc <- err
+		}()
+
+		// Give Receive a chance to block before closing
+		time.Sleep(10 * time.Millisecond)
+		if err := a.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+
+		select {
+		case err := <-errc:
+			if err == nil {
+				t.Fatal("Receive returned nil error after Close")
+			}
+		case <-time.After(conformanceTimeout):
+			t.Fatal("Receive still blocked after Close")
+		}
+	})
+
+	t.Run("ContextCancellation", func(t *testing.T) {
+		a, b := factory()
+		defer a.Close()
+		defer b.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errc := make(chan error, 1)
+		go func() {
+			_, err := a.Receive(ctx)
+			errc <- err
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+
+		select {
+		case err := <-errc:
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("Receive error = %v, want context.Canceled", err)
+			}
+		case <-time.After(conformanceTimeout):
+			t.Fatal("Receive still blocked after context cancellation")
+		}
+	})
+}
+
+// testRoundTrip sends a request from one transport and checks that the other
+// receives an equivalent message
+func testRoundTrip(t *testing.T, from, to mcp.Transport) {
+	t.Helper()
+
+	want := &mcp.Message{
+		ID:      json.RawMessage(`1`),
+		JSONRPC: "2.0",
+		Method:  "tools/call",
+		Params:  json.RawMessage(`{"name":"echo","arguments":{"text":"hello"}}`),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), conformanceTimeout)
+	defer cancel()
+
+	errc := make(chan error, 1)
+	go func() { errc <- from.Send(ctx, want) }()
+
+	got, err := to.Receive(ctx)
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if err := equalMessages(got, want); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testOrdering sends a sequence of notifications and checks they arrive in
+// the same order
+func testOrdering(t *testing.T, from, to mcp.Transport) {
+	t.Helper()
+
+	const n = 100
+
+	ctx, cancel := context.WithTimeout(context.Background(), conformanceTimeout)
+	defer cancel()
+
+	errc := make(chan error, 1)
+	go func() {
+		for i := 0; i < n; i++ {
+			msg := &mcp.Message{
+				JSONRPC: "2.0",
+				Method:  fmt.Sprintf("test/%d", i),
+			}
+			if err := from.Send(ctx, msg); err != nil {
+				errc <- err
+				return
+			}
+		}
+		errc <- nil
+	}()
+
+	for i := 0; i < n; i++ {
+		msg, err := to.Receive(ctx)
+		if err != nil {
+			t.Fatalf("Receive %d: %v", i, err)
+		}
+		if want := fmt.Sprintf("test/%d", i); msg.Method != want {
+			t.Fatalf("message %d: method = %q, want %q", i, msg.Method, want)
+		}
+	}
+
+	if err := <-errc; err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+}
+
+// equalMessages compares messages by their wire representation
+func equalMessages(got, want *mcp.Message) error {
+	gotBytes, err := json.Marshal(got)
+	if err != nil {
+		return err
+	}
+	wantBytes, err := json.Marshal(want)
+	if err != nil {
+		return err
+	}
+
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(gotBytes, &gotValue); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(wantBytes, &wantValue); err != nil {
+		return err
+	}
+
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		return fmt.Errorf("received %s, want %s", gotBytes, wantBytes)
+	}
+
+	return nil
+}
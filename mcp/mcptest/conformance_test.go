@@ -0,0 +1,80 @@
+package mcptest
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/paulsmith/mcp-go/mcp"
+)
+
+func TestInMemoryTransportConformance(t *testing.T) {
+	RunTransportConformance(t, func() (mcp.Transport, mcp.Transport) {
+		return mcp.NewInMemoryTransportPair()
+	})
+}
+
+func TestStdioTransportConformance(t *testing.T) {
+	RunTransportConformance(t, func() (mcp.Transport, mcp.Transport) {
+		aToB := newPipe(t)
+		bToA := newPipe(t)
+
+		a := mcp.NewStdioTransportWithIO(bToA.r, aToB.w)
+		b := mcp.NewStdioTransportWithIO(aToB.r, bToA.w)
+		return a, b
+	})
+}
+
+// pipe is an os.Pipe closed when the test ends
+type pipe struct {
+	r, w *os.File
+}
+
+func newPipe(t *testing.T) pipe {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		r.Close()
+		w.Close()
+	})
+	return pipe{r, w}
+}
+
+func TestEqualMessages(t *testing.T) {
+	base := &mcp.Message{
+		ID:      json.RawMessage(`1`),
+		JSONRPC: "2.0",
+		Method:  "tools/call",
+		Params:  json.RawMessage(`{"a":1,"b":[true,null]}`),
+	}
+
+	// Formatting differences are not differences on the wire
+	same := *base
+	same.Params = json.RawMessage(`{ "b": [true, null], "a": 1 }`)
+	if err := equalMessages(&same, base); err != nil {
+		t.Errorf("reordered params: %v", err)
+	}
+
+	for name, mutate := range map[string]func(*mcp.Message){
+		"id":     func(m *mcp.Message) { m.ID = json.RawMessage(`"1"`) },
+		"method": func(m *mcp.Message) { m.Method = "tools/list" },
+		"params": func(m *mcp.Message) { m.Params = json.RawMessage(`{"a":2,"b":[true,null]}`) },
+		"result": func(m *mcp.Message) { m.Result = json.RawMessage(`{}`) },
+	} {
+		changed := *base
+		mutate(&changed)
+		if err := equalMessages(&changed, base); err == nil {
+			t.Errorf("changed %s: messages compared equal", name)
+		}
+	}
+}
+
+func TestInMemoryTransportWithCodecConformance(t *testing.T) {
+	RunTransportConformance(t, func() (mcp.Transport, mcp.Transport) {
+		return mcp.NewInMemoryTransportPairWithCodec(mcp.JSONCodec)
+	})
+}
@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// InMemoryTransport implements the Transport interface by passing messages
+// over channels to a peer in the same process. It is mainly useful for tests
+// and for embedding a server alongside its client.
+type InMemoryTransport struct {
+	incoming <-chan *Message
+	outgoing chan<- *Message
+
+	codec  Codec
+	done   chan struct{}
+	closed sync.Once
+	peer   *InMemoryTransport
+}
+
+// NewInMemoryTransportPair creates two connected transports. Messages sent on
+// one are received on the other.
+func NewInMemoryTransportPair() (*InMemoryTransport, *InMemoryTransport) {
+	return NewInMemoryTransportPairWithCodec(nil)
+}
+
+// NewInMemoryTransportPairWithCodec creates two connected transports that
+// serialize every message with codec, as a network transport would. A nil
+// codec passes messages through unserialized.
+func NewInMemoryTransportPairWithCodec(codec Codec) (*InMemoryTransport, *InMemoryTransport) {
+	aToB := make(chan *Message, 16)
+	bToA := make(chan *Message, 16)
+
+	a := &InMemoryTransport{
+		incoming: bToA,
+		outgoing: aToB,
+		codec:    codec,
+		done:     make(chan struct{}),
+	}
+	b := &InMemoryTransport{
+		incoming: aToB,
+		outgoing: bToA,
+		codec:    codec,
+		done:     make(chan struct{}),
+	}
+	a.peer = b
+	b.peer = a
+
+	return a, b
+}
+
+// Kind returns "memory"
+func (t *InMemoryTransport) Kind() string {
+	return "memory"
+}
+
+// Send transmits a message through the transport
+func (t *InMemoryTransport) Send(ctx context.Context, msg *Message) error {
+	select {
+	case <-t.done:
+		return ErrTransportClosed
+	case <-t.peer.done:
+		return ErrTransportClosed
+	default:
+	}
+
+	// The peer receives what the codec decodes, not the sender's message
+	if t.codec != nil {
+		data, err := t.codec.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		msg = &Message{}
+		if err := t.codec.Unmarshal(data, msg); err != nil {
+			return err
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.done:
+		return ErrTransportClosed
+	case <-t.peer.done:
+		return ErrTransportClosed
+	case t.outgoing <- msg:
+		return nil
+	}
+}
+
+// Receive waits for and returns the next incoming message
+func (t *InMemoryTransport) Receive(ctx context.Context) (*Message, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-t.done:
+		return nil, ErrTransportClosed
+	case msg := <-t.incoming:
+		return msg, nil
+	case <-t.peer.done:
+		// Deliver anything the peer sent before closing
+		select {
+		case msg := <-t.incoming:
+			return msg, nil
+		default:
+			return nil, io.EOF
+		}
+	}
+}
+
+// Close terminates the transport connection. The peer observes io.EOF once
+// it has drained any pending messages.
+func (t *InMemoryTransport) Close() error {
+	t.closed.Do(func() { close(t.done) })
+	return nil
+}
@@ -0,0 +1,275 @@
+package mcp
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ServerOption configures optional Server behavior
+type ServerOption func(*Server)
+
+// WithInitializeTimeout closes the connection if the client does not send an
+// initialize request within d of Connect. This protects against half-open
+// connections on network transports.
+func WithInitializeTimeout(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.initializeTimeout = d
+	}
+}
+
+// WithIdleTimeout closes the connection if no message arrives from the
+// client for d. The timer restarts with every inbound message, and the
+// OnDisconnect hook fires when it expires.
+func WithIdleTimeout(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.idleTimeout = d
+	}
+}
+
+// WithBaseURL makes relative resource URIs, those without a scheme such as
+// "docs/readme.md", absolute by prefixing them with base in resources/list
+// and resources/read responses. URIs rooted at "/" are left as they are.
+// Reads of the absolute form are served by the resource registered under the
+// relative URI.
+func WithBaseURL(base string) ServerOption {
+	return func(s *Server) {
+		s.baseURL = strings.TrimSuffix(base, "/") + "/"
+	}
+}
+
+// WithRequestTimeout bounds how long any request handler may run. Clients
+// may ask for a shorter limit with _meta.timeoutMs in the request params.
+func WithRequestTimeout(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.requestTimeout = d
+	}
+}
+
+// WithReadOnly restricts the server to tools annotated with ReadOnlyHint.
+// Other tools are hidden from tools/list and calls to them are rejected.
+func WithReadOnly() ServerOption {
+	return func(s *Server) {
+		s.readOnly = true
+	}
+}
+
+// WithoutResources omits the resources capability from the initialize
+// response. Resource requests are still served.
+func WithoutResources() ServerOption {
+	return func(s *Server) {
+		delete(s.capabilities, "resources")
+	}
+}
+
+// WithoutTools omits the tools capability from the initialize response.
+// Tool requests are still served.
+func WithoutTools() ServerOption {
+	return func(s *Server) {
+		delete(s.capabilities, "tools")
+	}
+}
+
+// WithoutPrompts omits the prompts capability from the initialize response.
+// Prompt requests are still served.
+func WithoutPrompts() ServerOption {
+	return func(s *Server) {
+		delete(s.capabilities, "prompts")
+	}
+}
+
+// WithoutLogging omits the logging capability from the initialize response.
+// Logging requests are still served.
+func WithoutLogging() ServerOption {
+	return func(s *Server) {
+		delete(s.capabilities, "logging")
+	}
+}
+
+// WithErrorLogging reports internal failures that have no request to answer,
+// such as a panicking notification handler or a failed send, to the client
+// as error-level log messages from the "mcp" logger. It is off by default
+// because the messages may reveal server internals.
+func WithErrorLogging() ServerOption {
+	return func(s *Server) {
+		s.errorLogging = true
+	}
+}
+
+// WithDebugErrors adds debugging details to the data of error responses for
+// failed handlers: the chain of wrapped error messages, and a stack trace
+// when a handler panicked. It is meant for development only and is off by
+// default, since the details expose server internals.
+func WithDebugErrors() ServerOption {
+	return func(s *Server) {
+		s.debugErrors = true
+	}
+}
+
+// WithMaxToolOutputBytes limits the serialized size of a tool's result
+// content. Larger output is replaced with an error result saying how big it
+// was, so a runaway tool cannot overwhelm the client.
+func WithMaxToolOutputBytes(n int) ServerOption {
+	return func(s *Server) {
+		s.maxToolOutputBytes = n
+	}
+}
+
+// WithStrictToolResults answers tool calls whose handler returned nil
+// content and a nil error with a -32603 error. By default they produce a
+// result with an empty content array, since clients reject a null one.
+func WithStrictToolResults() ServerOption {
+	return func(s *Server) {
+		s.strictToolResults = true
+	}
+}
+
+// WithIdempotentToolCache serves repeated calls to tools annotated with
+// IdempotentHint from a cache when the arguments match a successful call made
+// within ttl, without running the handler again
+func WithIdempotentToolCache(ttl time.Duration) ServerOption {
+	return func(s *Server) {
+		s.toolResultCache = newToolResultCache(ttl)
+	}
+}
+
+// WithPromptContentNegotiation downgrades prompt message content that the
+// client cannot render. A client lists the content types it supports, such
+// as ["text"], under the experimental contentTypes capability; other content
+// is replaced with a text placeholder like "[image content omitted]".
+// Clients that do not declare contentTypes are sent everything.
+func WithPromptContentNegotiation() ServerOption {
+	return func(s *Server) {
+		s.negotiatePromptContent = true
+	}
+}
+
+// WithAuditLogger records every tool call with logger. The caller's
+// identity comes from the context given to Connect; see WithIdentity.
+func WithAuditLogger(logger AuditLogger) ServerOption {
+	return func(s *Server) {
+		s.auditLogger = logger
+	}
+}
+
+// WithArgumentCoercion converts tool arguments to the types declared in the
+// tool's input schema before the handler runs, so that a client sending "5"
+// for a number field or "true" for a boolean field is accepted
+func WithArgumentCoercion() ServerOption {
+	return func(s *Server) {
+		s.coerceArguments = true
+	}
+}
+
+// WithCapabilities merges capabilities into those advertised in the
+// initialize response. Nested objects are merged key by key, so
+// {"resources": {"listChanged": true}} adds to the resources capability
+// rather than replacing it.
+func WithCapabilities(capabilities map[string]interface{}) ServerOption {
+	return func(s *Server) {
+		mergeCapabilities(s.capabilities, capabilities)
+	}
+}
+
+// WithExperimentalCapability advertises a nonstandard capability under the
+// experimental key of the initialize response
+func WithExperimentalCapability(name string, value interface{}) ServerOption {
+	return WithCapabilities(map[string]interface{}{
+		"experimental": map[string]interface{}{name: value},
+	})
+}
+
+// mergeCapabilities deep-merges src into dst. Where both hold an object
+// under the same key the objects are merged; otherwise src's value wins.
+// Objects from src are copied so that later merges never modify them.
+func mergeCapabilities(dst, src map[string]interface{}) {
+	for key, value := range src {
+		srcObj, ok := value.(map[string]interface{})
+		if !ok {
+			dst[key] = value
+			continue
+		}
+
+		dstObj, ok := dst[key].(map[string]interface{})
+		if !ok {
+			dstObj = make(map[string]interface{}, len(srcObj))
+			dst[key] = dstObj
+		}
+		mergeCapabilities(dstObj, srcObj)
+	}
+}
+
+// WithSerialMethod makes the server handle requests for method one at a
+// time in the order they arrive, instead of concurrently. Requests for other
+// methods are unaffected.
+func WithSerialMethod(method string) ServerOption {
+	return func(s *Server) {
+		if s.serialMethods == nil {
+			s.serialMethods = make(map[string]bool)
+		}
+		s.serialMethods[method] = true
+	}
+}
+
+// WithProtocolVersion sets the protocol version the server advertises in
+// its initialize response, whatever version the client requests, for testing
+// clients against older versions. It panics if the version is not one the
+// package supports.
+func WithProtocolVersion(version string) ServerOption {
+	if !supportedProtocolVersions[version] {
+		panic(fmt.Sprintf("mcp: unsupported protocol version %q", version))
+	}
+	return func(s *Server) {
+		s.protocolVersion = version
+	}
+}
+
+// WithSendLimit bounds the number of outbound messages that may be waiting
+// on a slow client at once. When the limit is reached, notifications such as
+// progress updates and log messages fail with ErrSendQueueFull instead of
+// piling up, while responses and requests block until there is room.
+// PendingSends reports the current depth. A limit below 1 is treated as 1,
+// since an unbuffered queue could never accept a message.
+func WithSendLimit(n int) ServerOption {
+	if n < 1 {
+		n = 1
+	}
+	return func(s *Server) {
+		s.sendSlots = make(chan struct{}, n)
+	}
+}
+
+// WithDebugEcho registers the debug/echo tool from DebugEchoTool, for
+// checking connectivity while wiring up a client. Leave it off in
+// production.
+func WithDebugEcho() ServerOption {
+	return func(s *Server) {
+		tool, handler := DebugEchoTool()
+		s.AddTool(tool.Name, tool.Description, tool.InputSchema, handler, WithToolAnnotations(*tool.Annotations))
+	}
+}
+
+// WithIDGenerator sets how the server allocates IDs for requests it sends to
+// the client. The generator must return a JSON string or number that is
+// unique for the life of the connection. The default yields increasing
+// numbers; UUIDGenerator yields UUID strings.
+func WithIDGenerator(generate func() json.RawMessage) ServerOption {
+	return func(s *Server) {
+		s.idGenerator = generate
+	}
+}
+
+// UUIDGenerator returns random (version 4) UUIDs as JSON strings, for use
+// with WithIDGenerator
+func UUIDGenerator() json.RawMessage {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+
+	return json.RawMessage(fmt.Sprintf(`"%x-%x-%x-%x-%x"`, b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]))
+}
@@ -3,6 +3,8 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 )
 
 // PromptHandler is a function that handles prompt requests
@@ -43,6 +45,10 @@ func (s *Server) handleListPrompts(ctx context.Context, msg *Message) {
 
 // handleGetPrompt handles a prompts/get request
 func (s *Server) handleGetPrompt(ctx context.Context, msg *Message) {
+	if !s.requireParams(ctx, msg) {
+		return
+	}
+
 	// Parse request
 	var params struct {
 		Name      string                 `json:"name"`
@@ -66,11 +72,28 @@ func (s *Server) handleGetPrompt(ctx context.Context, msg *Message) {
 
 	// Execute the prompt handler
 	messages, err := handler(ctx, params.Arguments)
+	if errors.Is(err, ErrInvalidArguments) {
+		s.sendError(ctx, msg.ID, -32602, err.Error())
+		return
+	}
 	if err != nil {
-		s.sendError(ctx, msg.ID, -32603, err.Error())
+		s.sendHandlerError(ctx, msg.ID, -32603, err.Error(), err)
 		return
 	}
 
+	// Clients only understand the standard roles
+	for _, m := range messages {
+		if m.Role != RoleUser && m.Role != RoleAssistant {
+			s.sendError(ctx, msg.ID, -32603, fmt.Sprintf("Prompt returned invalid role %q", m.Role))
+			return
+		}
+	}
+
+	// Replace content the client says it cannot render
+	if s.negotiatePromptContent {
+		messages = s.downgradePromptContent(messages)
+	}
+
 	// Return the prompt result
 	result := struct {
 		Messages []PromptMessage `json:"messages"`
@@ -81,12 +104,46 @@ func (s *Server) handleGetPrompt(ctx context.Context, msg *Message) {
 	s.sendResult(ctx, msg.ID, result)
 }
 
-// NotifyPromptsChanged sends a notification that the prompts list has changed
-func (s *Server) NotifyPromptsChanged(ctx context.Context) error {
-	notification := &Message{
-		JSONRPC: "2.0",
-		Method:  "notifications/prompts/list_changed",
+// downgradePromptContent replaces prompt message content whose type is not
+// in the client's experimental contentTypes capability with a text
+// placeholder. Clients that do not declare contentTypes get the messages
+// unchanged.
+func (s *Server) downgradePromptContent(messages []PromptMessage) []PromptMessage {
+	declared, ok := s.ClientExperimentalCapability("contentTypes")
+	if !ok {
+		return messages
+	}
+	list, _ := declared.([]interface{})
+
+	supported := map[string]bool{"text": true}
+	for _, t := range list {
+		if name, ok := t.(string); ok {
+			supported[name] = true
+		}
+	}
+
+	downgraded := make([]PromptMessage, len(messages))
+	for i, m := range messages {
+		downgraded[i] = m
+
+		var content struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(m.Content, &content); err != nil || supported[content.Type] {
+			continue
+		}
+
+		placeholder, _ := marshalJSON(TextContent{
+			Type: "text",
+			Text: fmt.Sprintf("[%s content omitted]", content.Type),
+		})
+		downgraded[i].Content = placeholder
 	}
 
-	return s.transport.Send(ctx, notification)
+	return downgraded
+}
+
+// NotifyPromptsChanged sends a notification that the prompts list has changed
+func (s *Server) NotifyPromptsChanged(ctx context.Context) error {
+	return s.notify(ctx, "notifications/prompts/list_changed", nil)
 }
@@ -0,0 +1,98 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+// promptResult is a decoded prompts/get result
+type promptResult struct {
+	Messages []PromptMessage `json:"messages"`
+}
+
+// getPrompt gets the named prompt with no arguments
+func (c *testClient) getPrompt(name string) promptResult {
+	c.t.Helper()
+
+	var result promptResult
+	c.result("prompts/get", map[string]interface{}{"name": name}, &result)
+	return result
+}
+
+// staticPrompt returns a handler that always produces messages
+func staticPrompt(messages ...PromptMessage) PromptHandler {
+	return func(context.Context, map[string]interface{}) ([]PromptMessage, error) {
+		return messages, nil
+	}
+}
+
+func TestPromptRoles(t *testing.T) {
+	s := NewServer("test", "1.0")
+	s.AddPrompt("dialog", "", nil, staticPrompt(
+		textMessage(RoleUser, "What is Go?"),
+		textMessage(RoleAssistant, "A programming language."),
+		textMessage(RoleUser, "Who made it?"),
+	))
+	s.AddPrompt("typo", "", nil, staticPrompt(textMessage("User", "hi")))
+	s.AddPrompt("system", "", nil, staticPrompt(textMessage("system", "be nice")))
+	c := newTestClient(t, s)
+
+	messages := c.getPrompt("dialog").Messages
+	if len(messages) != 3 || messages[1].Role != RoleAssistant {
+		t.Errorf("dialog = %+v", messages)
+	}
+
+	for _, name := range []string{"typo", "system"} {
+		c.callError("prompts/get", map[string]interface{}{"name": name}, -32603)
+	}
+}
+
+func TestPromptContentNegotiation(t *testing.T) {
+	image := PromptMessage{Role: RoleUser, Content: json.RawMessage(`{"type":"image","data":"AAAA","mimeType":"image/png"}`)}
+	newServer := func(opts ...ServerOption) *Server {
+		s := NewServer("test", "1.0", opts...)
+		s.AddPrompt("chart", "", nil, staticPrompt(textMessage(RoleUser, "Explain this chart"), image))
+		return s
+	}
+	contentType := func(m PromptMessage) string {
+		var content struct{ Type, Text string }
+		json.Unmarshal(m.Content, &content)
+		return content.Type + ":" + content.Text
+	}
+	textOnly := map[string]interface{}{
+		"experimental": map[string]interface{}{"contentTypes": []string{"text"}},
+	}
+
+	c := connect(t, newServer(WithPromptContentNegotiation()))
+	c.initializeWith(textOnly)
+	c.notify("notifications/initialized", nil)
+	messages := c.getPrompt("chart").Messages
+	if got := contentType(messages[0]); got != "text:Explain this chart" {
+		t.Errorf("text message became %s", got)
+	}
+	if got := contentType(messages[1]); got != "text:[image content omitted]" {
+		t.Errorf("image message became %s", got)
+	}
+
+	// Clients that declare image support, or nothing, get the image
+	for _, caps := range []map[string]interface{}{
+		{"experimental": map[string]interface{}{"contentTypes": []string{"text", "image"}}},
+		{},
+	} {
+		c := connect(t, newServer(WithPromptContentNegotiation()))
+		c.initializeWith(caps)
+		c.notify("notifications/initialized", nil)
+		if got := contentType(c.getPrompt("chart").Messages[1]); got != "image:" {
+			t.Errorf("capabilities %v: image message became %s", caps, got)
+		}
+	}
+
+	// Without the option content is never changed
+	c = connect(t, newServer())
+	c.initializeWith(textOnly)
+	c.notify("notifications/initialized", nil)
+	if got := contentType(c.getPrompt("chart").Messages[1]); got != "image:" {
+		t.Errorf("without negotiation: image message became %s", got)
+	}
+}
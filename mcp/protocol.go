@@ -2,7 +2,9 @@
 package mcp
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 )
 
@@ -11,6 +13,14 @@ const (
 	ProtocolVersion = "2024-11-05"
 )
 
+// supportedProtocolVersions are the versions a server agrees to when a
+// client requests them, and may be pinned to with WithProtocolVersion. A
+// version is only listed once the server meets its requirements; 2025-03-26,
+// for one, needs JSON-RPC batches, which the server rejects.
+var supportedProtocolVersions = map[string]bool{
+	"2024-11-05": true,
+}
+
 // Message represents a protocol message
 type Message struct {
 	ID      json.RawMessage `json:"id,omitempty"`
@@ -39,6 +49,77 @@ func (m *Message) GetIDString() string {
 	return string(m.ID)
 }
 
+// ErrInvalidMessage is returned by ParseMessage for data that is valid JSON
+// but not a well-formed JSON-RPC 2.0 message
+var ErrInvalidMessage = errors.New("mcp: invalid message")
+
+// ParseMessage decodes and validates a single JSON-RPC 2.0 message. It checks
+// that jsonrpc is "2.0", that the id is a string, number or null, that
+// params is structured, and that the message is exactly one of a request, a
+// notification or a response carrying either a result or an error. Transports
+// use it so that malformed input is rejected as a whole rather than handled
+// in part.
+func ParseMessage(data []byte) (*Message, error) {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+
+	if msg.JSONRPC != "2.0" {
+		return nil, fmt.Errorf("%w: jsonrpc must be \"2.0\"", ErrInvalidMessage)
+	}
+
+	if msg.ID != nil {
+		switch msg.ID[0] {
+		case '"', 'n', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		default:
+			return nil, fmt.Errorf("%w: id must be a string or number", ErrInvalidMessage)
+		}
+	}
+
+	if msg.Params != nil {
+		switch msg.Params[0] {
+		case '{', '[', 'n':
+		default:
+			return nil, fmt.Errorf("%w: params must be an object or array", ErrInvalidMessage)
+		}
+	}
+
+	hasResult, hasError := msg.Result != nil, msg.Error != nil
+	switch {
+	case msg.Method != "":
+		if hasResult || hasError {
+			return nil, fmt.Errorf("%w: request has a result or error", ErrInvalidMessage)
+		}
+		if string(msg.ID) == "null" {
+			return nil, fmt.Errorf("%w: request id is null", ErrInvalidMessage)
+		}
+	case hasResult && hasError:
+		return nil, fmt.Errorf("%w: response has both a result and an error", ErrInvalidMessage)
+	case !hasResult && !hasError:
+		return nil, fmt.Errorf("%w: message has no method, result or error", ErrInvalidMessage)
+	case msg.ID == nil:
+		return nil, fmt.Errorf("%w: response has no id", ErrInvalidMessage)
+	case hasResult && string(msg.ID) == "null":
+		return nil, fmt.Errorf("%w: result response id is null", ErrInvalidMessage)
+	}
+
+	return &msg, nil
+}
+
+// marshalJSON is like json.Marshal but leaves HTML characters unescaped.
+// Transports decide whether to escape them when writing the message out.
+func marshalJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
 // ErrorMessage represents an error response
 type ErrorMessage struct {
 	Code    int             `json:"code"`
@@ -46,6 +127,11 @@ type ErrorMessage struct {
 	Data    json.RawMessage `json:"data,omitempty"`
 }
 
+// Error implements the error interface for errors returned by the client
+func (e *ErrorMessage) Error() string {
+	return fmt.Sprintf("mcp: error %d: %s", e.Code, e.Message)
+}
+
 // ServerInfo contains information about the server
 type ServerInfo struct {
 	Name    string `json:"name"`
@@ -58,19 +144,33 @@ type Resource struct {
 	Name        string `json:"name"`
 	Description string `json:"description,omitempty"`
 	MIMEType    string `json:"mimeType,omitempty"`
+	Size        int64  `json:"size,omitempty"`
 }
 
 // Tool represents a tool that can be called by clients
 type Tool struct {
-	Name        string          `json:"name"`
-	Description string          `json:"description,omitempty"`
-	InputSchema json.RawMessage `json:"inputSchema"`
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	InputSchema json.RawMessage   `json:"inputSchema"`
+	Annotations *ToolAnnotations  `json:"annotations,omitempty"`
+	Examples    []json.RawMessage `json:"examples,omitempty"`
+}
+
+// ToolAnnotations describe a tool's behavior to clients. They are hints and
+// not guarantees. Unset DestructiveHint and OpenWorldHint default to true.
+type ToolAnnotations struct {
+	Title           string `json:"title,omitempty"`
+	ReadOnlyHint    bool   `json:"readOnlyHint,omitempty"`
+	DestructiveHint *bool  `json:"destructiveHint,omitempty"`
+	IdempotentHint  bool   `json:"idempotentHint,omitempty"`
+	OpenWorldHint   *bool  `json:"openWorldHint,omitempty"`
 }
 
 // ToolContent represents content returned by a tool
 type ToolContent struct {
-	Type string `json:"type"`
-	Text string `json:"text,omitempty"`
+	Type     string           `json:"type"`
+	Text     string           `json:"text,omitempty"`
+	Resource *ResourceContent `json:"resource,omitempty"`
 }
 
 // Prompt represents a prompt template
@@ -93,6 +193,12 @@ type PromptMessage struct {
 	Content json.RawMessage `json:"content"`
 }
 
+// Roles a prompt message may have
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
 // TextContent represents text content
 type TextContent struct {
 	Type string `json:"type"`
@@ -111,6 +217,18 @@ const (
 	LogLevelEmergency = "emergency"
 )
 
+// logLevelSeverity orders the logging levels from least to most severe
+var logLevelSeverity = map[string]int{
+	LogLevelDebug:     0,
+	LogLevelInfo:      1,
+	LogLevelNotice:    2,
+	LogLevelWarning:   3,
+	LogLevelError:     4,
+	LogLevelCritical:  5,
+	LogLevelAlert:     6,
+	LogLevelEmergency: 7,
+}
+
 // LoggingMessageParams represents the parameters for a logging message notification
 type LoggingMessageParams struct {
 	Level  string      `json:"level"`
@@ -0,0 +1,157 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var parseMessageTests = []struct {
+	name  string
+	input string
+	valid bool
+}{
+	{"request", `{"jsonrpc":"2.0","id":1,"method":"tools/list","params":{}}`, true},
+	{"string id", `{"jsonrpc":"2.0","id":"a","method":"ping"}`, true},
+	{"notification", `{"jsonrpc":"2.0","method":"notifications/initialized"}`, true},
+	{"result", `{"jsonrpc":"2.0","id":1,"result":{}}`, true},
+	{"error", `{"jsonrpc":"2.0","id":1,"error":{"code":-32600,"message":"bad"}}`, true},
+	{"error with null id", `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"parse"}}`, true},
+	{"array params", `{"jsonrpc":"2.0","id":1,"method":"m","params":[1,2]}`, true},
+	{"result and error", `{"jsonrpc":"2.0","id":1,"result":{},"error":{"code":1,"message":"x"}}`, false},
+	{"request with null id", `{"jsonrpc":"2.0","id":null,"method":"ping"}`, false},
+	{"result with null id", `{"jsonrpc":"2.0","id":null,"result":{}}`, false},
+	{"response without id", `{"jsonrpc":"2.0","result":{}}`, false},
+	{"object id", `{"jsonrpc":"2.0","id":{},"method":"ping"}`, false},
+	{"string params", `{"jsonrpc":"2.0","id":1,"method":"m","params":"x"}`, false},
+	{"number params", `{"jsonrpc":"2.0","id":1,"method":"m","params":5}`, false},
+	{"request with result", `{"jsonrpc":"2.0","id":1,"method":"m","result":{}}`, false},
+	{"empty", `{}`, false},
+	{"wrong version", `{"jsonrpc":"1.0","id":1,"method":"ping"}`, false},
+	{"missing version", `{"id":1,"method":"ping"}`, false},
+	{"truncated", `{"jsonrpc":"2.0","id":1,"meth`, false},
+	{"batch", `[{"jsonrpc":"2.0","id":1,"method":"ping"}]`, false},
+	{"not json", `hello`, false},
+}
+
+func TestParseMessage(t *testing.T) {
+	for _, tt := range parseMessageTests {
+		msg, err := ParseMessage([]byte(tt.input))
+		if tt.valid {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			} else if err := checkMessage(msg); err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: accepted %s", tt.name, tt.input)
+		}
+	}
+
+	// Structural problems are distinguishable from malformed JSON
+	if _, err := ParseMessage([]byte(`{"jsonrpc":"1.0","method":"m"}`)); !errors.Is(err, ErrInvalidMessage) {
+		t.Errorf("wrong version: got %v, want ErrInvalidMessage", err)
+	}
+}
+
+func FuzzParseMessage(f *testing.F) {
+	for _, tt := range parseMessageTests {
+		f.Add([]byte(tt.input))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		msg, err := ParseMessage(data)
+		if err != nil {
+			if msg != nil {
+				t.Fatalf("returned a message along with error %v", err)
+			}
+			return
+		}
+
+		if err := checkMessage(msg); err != nil {
+			t.Fatalf("accepted %q: %v", data, err)
+		}
+
+		// An accepted message survives re-encoding
+		encoded, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatalf("re-encoding %q: %v", data, err)
+		}
+		again, err := ParseMessage(encoded)
+		if err != nil {
+			t.Fatalf("re-encoded %q as %s, which is rejected: %v", data, encoded, err)
+		}
+		if again.Method != msg.Method || !sameJSON(again.ID, msg.ID) {
+			t.Fatalf("re-encoding changed %q into %s", data, encoded)
+		}
+	})
+}
+
+// checkMessage verifies the invariants ParseMessage documents for the
+// messages it accepts
+func checkMessage(msg *Message) error {
+	if msg.JSONRPC != "2.0" {
+		return errors.New("jsonrpc is not 2.0")
+	}
+
+	if msg.ID != nil {
+		var id interface{}
+		if err := json.Unmarshal(msg.ID, &id); err != nil {
+			return err
+		}
+		switch id.(type) {
+		case string, float64, nil:
+		default:
+			return errors.New("id is not a string, number or null")
+		}
+	}
+
+	if msg.Params != nil {
+		var params interface{}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return err
+		}
+		switch params.(type) {
+		case map[string]interface{}, []interface{}, nil:
+		default:
+			return errors.New("params is not structured")
+		}
+	}
+
+	hasResult, hasError := msg.Result != nil, msg.Error != nil
+	if msg.Method != "" {
+		if hasResult || hasError {
+			return errors.New("request has a result or error")
+		}
+		if string(msg.ID) == "null" {
+			return errors.New("request id is null")
+		}
+		return nil
+	}
+
+	switch {
+	case hasResult == hasError:
+		return errors.New("response needs exactly one of result and error")
+	case msg.ID == nil:
+		return errors.New("response has no id")
+	case hasResult && string(msg.ID) == "null":
+		return errors.New("result response id is null")
+	}
+	return nil
+}
+
+// sameJSON reports whether a and b encode the same value, ignoring
+// differences in escaping
+func sameJSON(a, b json.RawMessage) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
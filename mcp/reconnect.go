@@ -0,0 +1,336 @@
+package mcp
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ConnectionState describes the state of a ReconnectingTransport
+type ConnectionState int
+
+const (
+	StateDisconnected ConnectionState = iota
+	StateConnecting
+	StateConnected
+	StateClosed
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case StateDisconnected:
+		return "disconnected"
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// Default backoff bounds for ReconnectingTransport
+const (
+	DefaultMinBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff = 10 * time.Second
+)
+
+// ReconnectingTransport wraps a dialer and transparently re-establishes the
+// underlying transport when Send or Receive fails. Any error other than
+// context cancellation is treated as a broken connection. Reconnection
+// attempts back off exponentially between MinBackoff and MaxBackoff until
+// they succeed, the caller's context is done, or the transport is closed.
+//
+// The exported fields must be set before the transport is first used.
+type ReconnectingTransport struct {
+	// MinBackoff is the delay after the first failed dial. Values of zero
+	// or less mean DefaultMinBackoff.
+	MinBackoff time.Duration
+
+	// MaxBackoff caps the delay between dial attempts. It is raised to
+	// MinBackoff if smaller.
+	MaxBackoff time.Duration
+
+	// Handshake, if set, runs on every newly dialed transport before it is
+	// used. Clients use it to re-run the initialize handshake after the
+	// server restarts. A failed handshake counts as a failed dial.
+	Handshake func(ctx context.Context, t Transport) error
+
+	// OnStateChange, if set, is called whenever the connection state
+	// changes. It must not call back into the transport.
+	OnStateChange func(state ConnectionState)
+
+	dial func() (Transport, error)
+
+	mu      sync.Mutex
+	conn    Transport
+	gen     uint64
+	state   ConnectionState
+	dialing chan struct{} // closed when the dial in progress finishes
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewReconnectingTransport creates a transport that dials lazily on first use
+// and redials whenever the connection breaks
+func NewReconnectingTransport(dial func() (Transport, error)) *ReconnectingTransport {
+	return &ReconnectingTransport{
+		MinBackoff: DefaultMinBackoff,
+		MaxBackoff: DefaultMaxBackoff,
+		dial:       dial,
+		done:       make(chan struct{}),
+	}
+}
+
+// Send transmits a message through the transport. If the send fails the
+// connection is re-established and the send is retried once.
+func (t *ReconnectingTransport) Send(ctx context.Context, msg *Message) error {
+	for attempt := 0; ; attempt++ {
+		conn, gen, err := t.connection(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = conn.Send(ctx, msg)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil || attempt > 0 {
+			return err
+		}
+
+		t.broken(gen)
+	}
+}
+
+// Receive waits for and returns the next incoming message, reconnecting as
+// many times as needed
+func (t *ReconnectingTransport) Receive(ctx context.Context) (*Message, error) {
+	for {
+		conn, gen, err := t.connection(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		msg, err := conn.Receive(ctx)
+		if err == nil {
+			return msg, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		t.broken(gen)
+	}
+}
+
+// Close terminates the current connection and stops reconnecting
+func (t *ReconnectingTransport) Close() error {
+	t.closeOnce.Do(func() { close(t.done) })
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var err error
+	if t.conn != nil {
+		err = t.conn.Close()
+		t.conn = nil
+	}
+	t.setState(StateClosed)
+
+	return err
+}
+
+// connection returns the current transport, dialing a new one if needed.
+// Only one caller dials at a time; the others wait for its result.
+func (t *ReconnectingTransport) connection(ctx context.Context) (Transport, uint64, error) {
+	for {
+		// A closed transport never dials again
+		if t.closed() {
+			return nil, 0, ErrTransportClosed
+		}
+
+		t.mu.Lock()
+		if t.conn != nil {
+			conn, gen := t.conn, t.gen
+			t.mu.Unlock()
+			return conn, gen, nil
+		}
+
+		if dialing := t.dialing; dialing != nil {
+			t.mu.Unlock()
+			select {
+			case <-dialing:
+				continue
+			case <-ctx.Done():
+				return nil, 0, ctx.Err()
+			case <-t.done:
+				return nil, 0, ErrTransportClosed
+			}
+		}
+
+		dialing := make(chan struct{})
+		t.dialing = dialing
+		t.mu.Unlock()
+
+		conn, err := t.redial(ctx)
+
+		t.mu.Lock()
+		t.dialing = nil
+		close(dialing)
+		if err == nil {
+			select {
+			case <-t.done:
+				conn.Close()
+				err = ErrTransportClosed
+			default:
+				t.conn = conn
+				t.gen++
+				t.setState(StateConnected)
+			}
+		}
+		gen := t.gen
+		t.mu.Unlock()
+
+		if err != nil {
+			return nil, 0, err
+		}
+		return conn, gen, nil
+	}
+}
+
+// redial dials until a connection is established, backing off between
+// failed attempts. It runs without t.mu held, and gives up on a hung dial
+// when ctx is done or the transport is closed, so Close never waits on it.
+func (t *ReconnectingTransport) redial(ctx context.Context) (Transport, error) {
+	backoff := t.MinBackoff
+	if backoff <= 0 {
+		backoff = DefaultMinBackoff
+	}
+	maxBackoff := t.MaxBackoff
+	if maxBackoff < backoff {
+		maxBackoff = backoff
+	}
+
+	for {
+		if t.closed() {
+			return nil, ErrTransportClosed
+		}
+
+		t.mu.Lock()
+		t.setState(StateConnecting)
+		t.mu.Unlock()
+
+		conn, err := t.dialOnce(ctx)
+		if err == nil {
+			return conn, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err == ErrTransportClosed {
+			return nil, err
+		}
+
+		t.mu.Lock()
+		t.setState(StateDisconnected)
+		t.mu.Unlock()
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-t.done:
+			timer.Stop()
+			return nil, ErrTransportClosed
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// dialOnce dials and runs the handshake on a separate goroutine. If the
+// caller stops waiting, a connection that is established later is closed.
+func (t *ReconnectingTransport) dialOnce(ctx context.Context) (Transport, error) {
+	type dialResult struct {
+		conn Transport
+		err  error
+	}
+	result := make(chan dialResult, 1)
+
+	go func() {
+		conn, err := t.dial()
+		if err == nil && t.Handshake != nil {
+			if err = t.Handshake(ctx, conn); err != nil {
+				conn.Close()
+			}
+		}
+		result <- dialResult{conn, err}
+	}()
+
+	abandon := func() {
+		go func() {
+			if r := <-result; r.err == nil {
+				r.conn.Close()
+			}
+		}()
+	}
+
+	select {
+	case r := <-result:
+		return r.conn, r.err
+	case <-ctx.Done():
+		abandon()
+		return nil, ctx.Err()
+	case <-t.done:
+		abandon()
+		return nil, ErrTransportClosed
+	}
+}
+
+// closed reports whether Close has been called
+func (t *ReconnectingTransport) closed() bool {
+	select {
+	case <-t.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// broken discards the connection of the given generation so the next
+// operation redials. Stale generations are ignored, so concurrent failures
+// on the same connection only trigger one reconnect.
+func (t *ReconnectingTransport) broken(gen uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.gen != gen || t.conn == nil {
+		return
+	}
+
+	t.conn.Close()
+	t.conn = nil
+	t.setState(StateDisconnected)
+}
+
+// setState records a state transition and notifies the callback. A closed
+// transport stays closed. The caller must hold t.mu.
+func (t *ReconnectingTransport) setState(state ConnectionState) {
+	if t.state == state || t.state == StateClosed {
+		return
+	}
+	t.state = state
+
+	if t.OnStateChange != nil {
+		t.OnStateChange(state)
+	}
+}
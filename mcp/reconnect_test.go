@@ -0,0 +1,187 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stateRecorder collects the states reported to OnStateChange
+type stateRecorder struct {
+	mu     sync.Mutex
+	states []ConnectionState
+}
+
+func (r *stateRecorder) record(state ConnectionState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states = append(r.states, state)
+}
+
+func (r *stateRecorder) get() []ConnectionState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]ConnectionState(nil), r.states...)
+}
+
+func TestReconnectingTransportRetriesDial(t *testing.T) {
+	var dials, handshakes atomic.Int32
+	var peer *InMemoryTransport
+	rt := NewReconnectingTransport(func() (Transport, error) {
+		if dials.Add(1) <= 2 {
+			return nil, errors.New("connection refused")
+		}
+		a, b := NewInMemoryTransportPair()
+		peer = b
+		return a, nil
+	})
+	rt.MinBackoff = time.Millisecond
+	rt.Handshake = func(context.Context, Transport) error {
+		handshakes.Add(1)
+		return nil
+	}
+	var recorder stateRecorder
+	rt.OnStateChange = recorder.record
+	defer rt.Close()
+
+	ctx := context.Background()
+	if err := rt.Send(ctx, &Message{JSONRPC: "2.0", Method: "ping"}); err != nil {
+		t.Fatal(err)
+	}
+	if msg, err := peer.Receive(ctx); err != nil || msg.Method != "ping" {
+		t.Fatalf("peer received %v, %v", msg, err)
+	}
+
+	if n := dials.Load(); n != 3 {
+		t.Errorf("dialed %d times, want 3", n)
+	}
+	if n := handshakes.Load(); n != 1 {
+		t.Errorf("handshake ran %d times, want 1", n)
+	}
+	want := []ConnectionState{
+		StateConnecting, StateDisconnected,
+		StateConnecting, StateDisconnected,
+		StateConnecting, StateConnected,
+	}
+	if got := recorder.get(); !slices.Equal(got, want) {
+		t.Errorf("states = %v, want %v", got, want)
+	}
+}
+
+func TestReconnectingTransportRedialsBrokenConnection(t *testing.T) {
+	peers := make(chan *InMemoryTransport, 2)
+	rt := NewReconnectingTransport(func() (Transport, error) {
+		a, b := NewInMemoryTransportPair()
+		peers <- b
+		return a, nil
+	})
+	rt.MinBackoff = time.Millisecond
+	defer rt.Close()
+
+	received := make(chan *Message)
+	go func() {
+		msg, err := rt.Receive(context.Background())
+		if err != nil {
+			t.Error(err)
+		}
+		received <- msg
+	}()
+
+	// The first server goes away; the second answers
+	(<-peers).Close()
+	second := <-peers
+	second.Send(context.Background(), &Message{JSONRPC: "2.0", Method: "hello"})
+
+	select {
+	case msg := <-received:
+		if msg.Method != "hello" {
+			t.Errorf("received %q, want hello", msg.Method)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("Receive did not reconnect")
+	}
+}
+
+func TestReconnectingTransportCloseDuringDial(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	rt := NewReconnectingTransport(func() (Transport, error) {
+		<-release
+		a, _ := NewInMemoryTransportPair()
+		return a, nil
+	})
+	var recorder stateRecorder
+	rt.OnStateChange = recorder.record
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := rt.Receive(context.Background())
+		errs <- err
+	}()
+	waitFor(t, "dial to start", func() bool { return len(recorder.get()) > 0 })
+
+	closed := make(chan struct{})
+	go func() {
+		rt.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(testTimeout):
+		t.Fatal("Close blocked behind a hung dial")
+	}
+
+	select {
+	case err := <-errs:
+		if err != ErrTransportClosed {
+			t.Errorf("Receive = %v, want ErrTransportClosed", err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("Receive still waiting on a hung dial after Close")
+	}
+}
+
+func TestReconnectingTransportZeroBackoff(t *testing.T) {
+	var dials atomic.Int32
+	rt := NewReconnectingTransport(func() (Transport, error) {
+		dials.Add(1)
+		return nil, errors.New("connection refused")
+	})
+	rt.MinBackoff = 0
+	rt.MaxBackoff = 0
+	defer rt.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultMinBackoff/2)
+	defer cancel()
+	if _, err := rt.Receive(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Receive = %v, want context.DeadlineExceeded", err)
+	}
+	if n := dials.Load(); n > 2 {
+		t.Errorf("dialed %d times in %v; backoff not applied", n, DefaultMinBackoff/2)
+	}
+}
+
+func TestReconnectingTransportNoDialAfterClose(t *testing.T) {
+	var dials atomic.Int32
+	rt := NewReconnectingTransport(func() (Transport, error) {
+		dials.Add(1)
+		a, _ := NewInMemoryTransportPair()
+		return a, nil
+	})
+	rt.Close()
+
+	if err := rt.Send(context.Background(), &Message{JSONRPC: "2.0", Method: "hello"}); err != ErrTransportClosed {
+		t.Errorf("Send after Close = %v, want ErrTransportClosed", err)
+	}
+	if _, err := rt.Receive(context.Background()); err != ErrTransportClosed {
+		t.Errorf("Receive after Close = %v, want ErrTransportClosed", err)
+	}
+	if n := dials.Load(); n != 0 {
+		t.Errorf("dialed %d times after Close", n)
+	}
+}
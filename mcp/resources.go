@@ -3,24 +3,55 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"regexp"
 	"strings"
 )
 
+// ErrResourceNotFound can be returned (or wrapped) by a resource handler to
+// report that the requested resource does not exist. The client receives a
+// -32002 "Resource not found" error instead of an internal error.
+var ErrResourceNotFound = errors.New("resource not found")
+
 // ResourceHandler is a function that handles resource read requests for static URIs
 type ResourceHandler func(ctx context.Context, uri *url.URL) (ResourceContent, error)
 
 // ResourceTemplateHandler is a function that handles resource read requests for URI templates
 type ResourceTemplateHandler func(ctx context.Context, uri *url.URL, params map[string]string) (ResourceContent, error)
 
+// ResourceLister is a function that enumerates the concrete resources
+// available through a resource template
+type ResourceLister func(ctx context.Context) ([]Resource, error)
+
+// ResourceOption configures optional fields of a registered resource
+type ResourceOption func(*Resource)
+
+// WithResourceSize sets the size of the resource in bytes, which clients may
+// use to decide whether to read it
+func WithResourceSize(size int64) ResourceOption {
+	return func(r *Resource) {
+		r.Size = size
+	}
+}
+
 // ResourceContent represents content returned by a resource
 type ResourceContent struct {
 	URI      string `json:"uri"`
+	Name     string `json:"name,omitempty"`
+	Title    string `json:"title,omitempty"`
 	Text     string `json:"text,omitempty"`
 	Blob     []byte `json:"blob,omitempty"`
 	MIMEType string `json:"mimeType,omitempty"`
+
+	// LastModified is when the content last changed, in RFC 3339 format
+	LastModified string `json:"lastModified,omitempty"`
+
+	// ETag identifies this version of the content. A client that sends it
+	// back as _meta.ifNoneMatch gets a notModified result instead of the
+	// content when it has not changed.
+	ETag string `json:"etag,omitempty"`
 }
 
 // ResourceTemplate represents a URI template for dynamic resources
@@ -30,23 +61,79 @@ type ResourceTemplate struct {
 	paramNames  []string
 	Description string
 	MIMEType    string
+
+	// Lister optionally enumerates the concrete resources matching the
+	// template. It is called on every resources/list request.
+	Lister ResourceLister
+}
+
+// ResourceTemplateOption configures a resource template at creation
+type ResourceTemplateOption func(*templateOptions)
+
+// templateOptions collects the settings applied by ResourceTemplateOptions
+type templateOptions struct {
+	paramPatterns map[string]string
+}
+
+// WithParamPattern constrains the template parameter name to values matching
+// the regular expression pattern, such as `\d+`. URIs whose parameter does
+// not match fail to match the template and never reach its handler.
+func WithParamPattern(name, pattern string) ResourceTemplateOption {
+	return func(o *templateOptions) {
+		if o.paramPatterns == nil {
+			o.paramPatterns = make(map[string]string)
+		}
+		o.paramPatterns[name] = pattern
+	}
 }
 
-// NewResourceTemplate creates a new resource template
-func NewResourceTemplate(template, description, mimeType string) (*ResourceTemplate, error) {
+// NewResourceTemplate creates a new resource template. Each {param} in the
+// template matches one path segment; {param...} or {+param} matches one or
+// more segments, slashes included.
+func NewResourceTemplate(template, description, mimeType string, opts ...ResourceTemplateOption) (*ResourceTemplate, error) {
+	var o templateOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	// Extract parameter names from the template
 	paramPattern := regexp.MustCompile(`\{([^{}]+)\}`)
 	matches := paramPattern.FindAllStringSubmatch(template, -1)
 
+	// A parameter written {name...} or {+name} spans path segments
 	paramNames := make([]string, 0, len(matches))
+	multiSegment := make(map[string]bool)
 	for _, match := range matches {
-		paramNames = append(paramNames, match[1])
+		name := match[1]
+		if trimmed, ok := strings.CutSuffix(name, "..."); ok {
+			name = trimmed
+			multiSegment[name] = true
+		} else if trimmed, ok := strings.CutPrefix(name, "+"); ok {
+			name = trimmed
+			multiSegment[name] = true
+		}
+		paramNames = append(paramNames, name)
+	}
+
+	for name, pattern := range o.paramPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern for parameter %q: %w", name, err)
+		}
 	}
 
-	// Convert template to regex for matching
+	// Convert template to regex for matching. Each parameter is a named
+	// group so that groups inside constraint patterns don't shift them.
 	regexPattern := template
-	for _, param := range paramNames {
-		regexPattern = strings.Replace(regexPattern, "{"+param+"}", "([^/]+)", 1)
+	for i, param := range paramNames {
+		pattern, ok := o.paramPatterns[param]
+		if !ok {
+			pattern = "[^/]+"
+			if multiSegment[param] {
+				pattern = ".+"
+			}
+		}
+		group := fmt.Sprintf("(?P<p%d>%s)", i, pattern)
+		regexPattern = strings.Replace(regexPattern, "{"+matches[i][1]+"}", group, 1)
 	}
 	regexPattern = "^" + regexPattern + "$"
 
@@ -73,30 +160,69 @@ func (t *ResourceTemplate) Match(uri string) (map[string]string, bool) {
 
 	params := make(map[string]string)
 	for i, name := range t.paramNames {
-		params[name] = matches[i+1]
+		params[name] = matches[t.regex.SubexpIndex(fmt.Sprintf("p%d", i))]
 	}
 
 	return params, true
 }
 
+// ResourceDef describes a static resource for AddResources
+type ResourceDef struct {
+	URI         string
+	Name        string
+	Description string
+	MIMEType    string
+	Handler     ResourceHandler
+	Options     []ResourceOption
+}
+
 // AddResource registers a static resource with the server
-func (s *Server) AddResource(uri, name, description, mimeType string, handler ResourceHandler) {
+func (s *Server) AddResource(uri, name, description, mimeType string, handler ResourceHandler, opts ...ResourceOption) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.addResource(uri, name, description, mimeType, handler, opts)
+}
+
+// AddResources registers several static resources at once. Clients listing
+// resources see either none or all of them, and an initialized client gets a
+// single resources/list_changed notification for the whole batch.
+func (s *Server) AddResources(defs []ResourceDef) {
+	if len(defs) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	for _, def := range defs {
+		s.addResource(def.URI, def.Name, def.Description, def.MIMEType, def.Handler, def.Options)
+	}
+	s.mu.Unlock()
+
+	if s.ready.Load() {
+		s.NotifyResourcesChanged(context.Background())
+	}
+}
+
+// addResource registers a static resource. The caller must hold s.mu.
+func (s *Server) addResource(uri, name, description, mimeType string, handler ResourceHandler, opts []ResourceOption) {
 	resource := Resource{
 		URI:         uri,
 		Name:        name,
 		Description: description,
 		MIMEType:    mimeType,
 	}
+	for _, opt := range opts {
+		opt(&resource)
+	}
 
 	// Register the resource
 	s.resources = append(s.resources, resource)
 	s.resourceHandlers[uri] = handler
 }
 
-// AddResourceTemplate registers a dynamic resource template with the server
+// AddResourceTemplate registers a dynamic resource template with the server.
+// When templates overlap, a URI is read through the first one registered
+// that matches it, so register narrower templates before greedy ones.
 func (s *Server) AddResourceTemplate(template *ResourceTemplate, name string, handler ResourceTemplateHandler) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -109,18 +235,161 @@ func (s *Server) AddResourceTemplate(template *ResourceTemplate, name string, ha
 	}
 
 	// Register the resource template
+	if _, exists := s.resourceTemplates[template.Template]; !exists {
+		s.resourceTemplateOrder = append(s.resourceTemplateOrder, template.Template)
+	}
 	s.resourceTemplates[template.Template] = template
 	s.resourceTemplateHandlers[template.Template] = handler
 	s.resources = append(s.resources, resource)
 }
 
+// SetResourceProvider sets a function that lists resources on demand. Its
+// resources are added to the statically registered ones on every
+// resources/list request, so large or changing resource sets need not be
+// registered up front. Pair it with SetResourceReader to serve them.
+func (s *Server) SetResourceProvider(provider ResourceLister) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.resourceProvider = provider
+}
+
+// SetResourceReader sets a handler for reads of URIs that no registered
+// resource, template or prefix serves, such as those listed by a resource
+// provider. It should return ErrResourceNotFound for unknown URIs.
+func (s *Server) SetResourceReader(reader ResourceHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.resourceReader = reader
+}
+
+// resourcePrefix is a handler that claims every URI with a given prefix
+type resourcePrefix struct {
+	prefix  string
+	handler ResourceHandler
+}
+
+// AddResourcePrefix registers a handler for every URI that starts with
+// prefix, such as "db://". It is consulted only when no static resource or
+// template matches, and the longest matching prefix wins. The handler
+// receives the full URI. Prefix handlers do not appear in resources/list.
+func (s *Server) AddResourcePrefix(prefix string, handler ResourceHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.resourcePrefixes {
+		if existing.prefix == prefix {
+			s.resourcePrefixes[i].handler = handler
+			return
+		}
+	}
+	s.resourcePrefixes = append(s.resourcePrefixes, resourcePrefix{prefix: prefix, handler: handler})
+}
+
+// findResourcePrefix returns the handler for the longest registered prefix
+// of uri
+func (s *Server) findResourcePrefix(uri string) (ResourceHandler, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var best resourcePrefix
+	for _, p := range s.resourcePrefixes {
+		if strings.HasPrefix(uri, p.prefix) && (best.handler == nil || len(p.prefix) > len(best.prefix)) {
+			best = p
+		}
+	}
+
+	return best.handler, best.handler != nil
+}
+
+// uriPrefixAlias rewrites URIs starting with oldPrefix to start with newPrefix
+type uriPrefixAlias struct {
+	oldPrefix string
+	newPrefix string
+}
+
+// AddURIAlias makes reads of oldURI resolve as reads of newURI, so that
+// clients still using a URI from before a rename keep working
+func (s *Server) AddURIAlias(oldURI, newURI string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.uriAliases == nil {
+		s.uriAliases = make(map[string]string)
+	}
+	s.uriAliases[oldURI] = newURI
+}
+
+// AddURIPrefixAlias makes reads of any URI starting with oldPrefix resolve
+// with newPrefix in its place, such as "docs://" to "documents://". Exact
+// aliases take precedence, and the longest matching prefix wins.
+func (s *Server) AddURIPrefixAlias(oldPrefix, newPrefix string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.uriPrefixAliases {
+		if existing.oldPrefix == oldPrefix {
+			s.uriPrefixAliases[i].newPrefix = newPrefix
+			return
+		}
+	}
+	s.uriPrefixAliases = append(s.uriPrefixAliases, uriPrefixAlias{oldPrefix: oldPrefix, newPrefix: newPrefix})
+}
+
+// resolveURIAlias returns the URI that uri is an alias of, or uri itself
+func (s *Server) resolveURIAlias(uri string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if target, ok := s.uriAliases[uri]; ok {
+		return target
+	}
+
+	var best *uriPrefixAlias
+	for i, a := range s.uriPrefixAliases {
+		if strings.HasPrefix(uri, a.oldPrefix) && (best == nil || len(a.oldPrefix) > len(best.oldPrefix)) {
+			best = &s.uriPrefixAliases[i]
+		}
+	}
+	if best == nil {
+		return uri
+	}
+
+	return best.newPrefix + strings.TrimPrefix(uri, best.oldPrefix)
+}
+
 // handleListResources handles a resources/list request
 func (s *Server) handleListResources(ctx context.Context, msg *Message) {
 	s.mu.RLock()
 	resources := make([]Resource, len(s.resources))
 	copy(resources, s.resources)
+
+	var listers []ResourceLister
+	for _, resource := range resources {
+		if template, ok := s.resourceTemplates[resource.URI]; ok && template.Lister != nil {
+			listers = append(listers, template.Lister)
+		}
+	}
+	if s.resourceProvider != nil {
+		listers = append(listers, s.resourceProvider)
+	}
 	s.mu.RUnlock()
 
+	// Expand templates and the provider into their concrete resources
+	for _, lister := range listers {
+		concrete, err := lister(ctx)
+		if err != nil {
+			s.sendHandlerError(ctx, msg.ID, -32603, fmt.Sprintf("Error listing resources: %v", err), err)
+			return
+		}
+		resources = append(resources, concrete...)
+	}
+
+	for i := range resources {
+		resources[i].URI = s.absoluteURI(resources[i].URI)
+	}
+
 	// Build response
 	result := struct {
 		Resources []Resource `json:"resources"`
@@ -133,9 +402,17 @@ func (s *Server) handleListResources(ctx context.Context, msg *Message) {
 
 // handleReadResource handles a resources/read request
 func (s *Server) handleReadResource(ctx context.Context, msg *Message) {
+	if !s.requireParams(ctx, msg) {
+		return
+	}
+
 	// Parse request
 	var params struct {
-		URI string `json:"uri"`
+		URI    string   `json:"uri"`
+		Accept []string `json:"accept"`
+		Meta   struct {
+			IfNoneMatch string `json:"ifNoneMatch"`
+		} `json:"_meta"`
 	}
 
 	if err := json.Unmarshal(msg.Params, &params); err != nil {
@@ -143,8 +420,13 @@ func (s *Server) handleReadResource(ctx context.Context, msg *Message) {
 		return
 	}
 
-	// Parse URI
-	uri, err := url.Parse(params.URI)
+	// Handlers choose a representation with NegotiateMIMEType
+	if len(params.Accept) > 0 {
+		ctx = withAcceptedMIMETypes(ctx, params.Accept)
+	}
+
+	// Parse URI, following any alias left by a rename
+	uri, err := url.Parse(s.resolveURIAlias(s.relativeURI(params.URI)))
 	if err != nil {
 		s.sendError(ctx, msg.ID, -32602, "Invalid URI")
 		return
@@ -158,58 +440,147 @@ func (s *Server) handleReadResource(ctx context.Context, msg *Message) {
 	if exists {
 		content, err := handler(ctx, uri)
 		if err != nil {
-			s.sendError(ctx, msg.ID, -32603, fmt.Sprintf("Error reading resource: %v", err))
+			s.sendReadError(ctx, msg.ID, err)
 			return
 		}
 
-		result := struct {
-			Contents []ResourceContent `json:"contents"`
-		}{
-			Contents: []ResourceContent{content},
-		}
+		s.sendReadResult(ctx, msg.ID, content, params.Meta.IfNoneMatch)
+		return
+	}
 
-		s.sendResult(ctx, msg.ID, result)
+	// Then streamed resources
+	s.mu.RLock()
+	stream, exists := s.resourceStreams[uri.String()]
+	s.mu.RUnlock()
+
+	if exists {
+		s.readResourceStream(ctx, msg.ID, uri, stream)
 		return
 	}
 
-	// Try resource templates
+	// Try resource templates, in registration order
 	s.mu.RLock()
-	for templateStr, template := range s.resourceTemplates {
-		params, matches := template.Match(uri.String())
+	for _, templateStr := range s.resourceTemplateOrder {
+		templateParams, matches := s.resourceTemplates[templateStr].Match(uri.String())
 		if matches {
 			handler := s.resourceTemplateHandlers[templateStr]
 			s.mu.RUnlock()
 
-			content, err := handler(ctx, uri, params)
+			content, err := handler(ctx, uri, templateParams)
 			if err != nil {
-				s.sendError(ctx, msg.ID, -32603, fmt.Sprintf("Error reading resource: %v", err))
+				s.sendReadError(ctx, msg.ID, err)
 				return
 			}
 
-			result := struct {
-				Contents []ResourceContent `json:"contents"`
-			}{
-				Contents: []ResourceContent{content},
-			}
+			s.sendReadResult(ctx, msg.ID, content, params.Meta.IfNoneMatch)
+			return
+		}
+	}
+	s.mu.RUnlock()
 
-			s.sendResult(ctx, msg.ID, result)
+	// Finally catch-all prefixes
+	if handler, exists := s.findResourcePrefix(uri.String()); exists {
+		content, err := handler(ctx, uri)
+		if err != nil {
+			s.sendReadError(ctx, msg.ID, err)
 			return
 		}
+
+		s.sendReadResult(ctx, msg.ID, content, params.Meta.IfNoneMatch)
+		return
 	}
+
+	// And the resource reader for provided resources
+	s.mu.RLock()
+	reader := s.resourceReader
 	s.mu.RUnlock()
 
+	if reader != nil {
+		content, err := reader(ctx, uri)
+		if err != nil {
+			s.sendReadError(ctx, msg.ID, err)
+			return
+		}
+
+		s.sendReadResult(ctx, msg.ID, content, params.Meta.IfNoneMatch)
+		return
+	}
+
 	// Resource not found
 	s.sendError(ctx, msg.ID, -32602, "Resource not found")
 }
 
-// NotifyResourcesChanged sends a notification that the resources list has changed
-func (s *Server) NotifyResourcesChanged(ctx context.Context) error {
-	notification := &Message{
-		JSONRPC: "2.0",
-		Method:  "notifications/resources/list_changed",
+// sendReadResult sends the content of a resources/read request. If the
+// client already holds the content's current ETag, an empty result marked
+// notModified is sent instead.
+func (s *Server) sendReadResult(ctx context.Context, id json.RawMessage, content ResourceContent, ifNoneMatch string) {
+	type readMeta struct {
+		NotModified bool `json:"notModified"`
+	}
+
+	content.URI = s.absoluteURI(content.URI)
+
+	result := struct {
+		Contents []ResourceContent `json:"contents"`
+		Meta     *readMeta         `json:"_meta,omitempty"`
+	}{
+		Contents: []ResourceContent{content},
+	}
+
+	if content.ETag != "" && content.ETag == ifNoneMatch {
+		result.Contents = []ResourceContent{}
+		result.Meta = &readMeta{NotModified: true}
+	}
+
+	s.sendResult(ctx, id, result)
+}
+
+// absoluteURI prefixes a URI without a scheme with the base URL set by
+// WithBaseURL. URIs rooted at "/" are left alone, as stripping the slash
+// would make "/readme" and "readme" indistinguishable.
+func (s *Server) absoluteURI(uri string) string {
+	if s.baseURL == "" || uri == "" || strings.HasPrefix(uri, "/") || strings.Contains(uri, "://") {
+		return uri
+	}
+	return s.baseURL + uri
+}
+
+// relativeURI is the inverse of absoluteURI: it strips the base URL from
+// URIs that absoluteURI could have produced, unless a resource is registered
+// under the absolute URI itself
+func (s *Server) relativeURI(uri string) string {
+	if s.baseURL == "" {
+		return uri
 	}
 
-	return s.transport.Send(ctx, notification)
+	s.mu.RLock()
+	_, registered := s.resourceHandlers[uri]
+	s.mu.RUnlock()
+
+	if registered {
+		return uri
+	}
+	relative, ok := strings.CutPrefix(uri, s.baseURL)
+	if !ok || s.absoluteURI(relative) != uri {
+		return uri
+	}
+	return relative
+}
+
+// sendReadError reports a resource handler failure, distinguishing resources
+// the handler says do not exist from other errors
+func (s *Server) sendReadError(ctx context.Context, id json.RawMessage, err error) {
+	if errors.Is(err, ErrResourceNotFound) {
+		s.sendHandlerError(ctx, id, -32002, fmt.Sprintf("Resource not found: %v", err), err)
+		return
+	}
+
+	s.sendHandlerError(ctx, id, -32603, fmt.Sprintf("Error reading resource: %v", err), err)
+}
+
+// NotifyResourcesChanged sends a notification that the resources list has changed
+func (s *Server) NotifyResourcesChanged(ctx context.Context) error {
+	return s.notify(ctx, "notifications/resources/list_changed", nil)
 }
 
 // NotifyResourceUpdated sends a notification that a resource has been updated
@@ -220,16 +591,5 @@ func (s *Server) NotifyResourceUpdated(ctx context.Context, uri string) error {
 		URI: uri,
 	}
 
-	paramsBytes, err := json.Marshal(params)
-	if err != nil {
-		return err
-	}
-
-	notification := &Message{
-		JSONRPC: "2.0",
-		Method:  "notifications/resources/updated",
-		Params:  paramsBytes,
-	}
-
-	return s.transport.Send(ctx, notification)
+	return s.notify(ctx, "notifications/resources/updated", params)
 }
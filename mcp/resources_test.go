@@ -0,0 +1,516 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// readResult is a decoded resources/read result
+type readResult struct {
+	Contents []ResourceContent `json:"contents"`
+	Meta     struct {
+		NotModified bool `json:"notModified"`
+	} `json:"_meta"`
+}
+
+// listResources returns the resources the server lists
+func (c *testClient) listResources() []Resource {
+	c.t.Helper()
+
+	var result struct{ Resources []Resource }
+	c.result("resources/list", nil, &result)
+	return result.Resources
+}
+
+// readResource reads uri and returns its first content item
+func (c *testClient) readResource(uri string) ResourceContent {
+	c.t.Helper()
+
+	var result readResult
+	c.result("resources/read", map[string]interface{}{"uri": uri}, &result)
+	if len(result.Contents) == 0 {
+		c.t.Fatalf("reading %s: no contents", uri)
+	}
+	return result.Contents[0]
+}
+
+// textResource returns a handler that serves text as the resource's content
+func textResource(text string) ResourceHandler {
+	return func(_ context.Context, uri *url.URL) (ResourceContent, error) {
+		return ResourceContent{URI: uri.String(), Text: text}, nil
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	s := NewServer("test", "1.0", WithBaseURL("https://example.com/docs"))
+	s.AddResource("guide/intro.md", "intro", "", "text/markdown", textResource("intro"))
+	s.AddResource("/readme", "readme", "", "text/plain", textResource("readme"))
+	s.AddResource("file:///etc/motd", "motd", "", "text/plain", textResource("motd"))
+	c := newTestClient(t, s)
+
+	want := map[string]string{
+		"intro":  "https://example.com/docs/guide/intro.md",
+		"readme": "/readme",
+		"motd":   "file:///etc/motd",
+	}
+	for _, r := range c.listResources() {
+		if r.URI != want[r.Name] {
+			t.Errorf("%s listed as %q, want %q", r.Name, r.URI, want[r.Name])
+		}
+
+		// Every listed URI reads back
+		content := c.readResource(r.URI)
+		if content.Text != r.Name || content.URI != r.URI {
+			t.Errorf("reading %q: got %q from %q", r.URI, content.Text, content.URI)
+		}
+	}
+
+	// The relative form is still served
+	if content := c.readResource("guide/intro.md"); content.Text != "intro" {
+		t.Errorf("reading relative URI: got %q", content.Text)
+	}
+
+	// A URI that absoluteURI cannot produce is not mapped back
+	c.callError("resources/read", map[string]interface{}{"uri": "https://example.com/docs//readme"}, -32602)
+}
+
+func TestBaseURLInverse(t *testing.T) {
+	s := NewServer("test", "1.0", WithBaseURL("https://example.com/"))
+	for _, uri := range []string{"a", "a/b", "/a", "//a", "file:///a", "https://other/a"} {
+		if got := s.relativeURI(s.absoluteURI(uri)); got != uri {
+			t.Errorf("relativeURI(absoluteURI(%q)) = %q", uri, got)
+		}
+	}
+}
+
+func TestResourceTemplateLister(t *testing.T) {
+	var calls int
+	template, err := NewResourceTemplate("files://{name}", "files", "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.Lister = func(context.Context) ([]Resource, error) {
+		calls++
+		return []Resource{
+			{URI: "files://a", Name: "a"},
+			{URI: "files://b", Name: "b"},
+			{URI: "files://c", Name: "c"},
+		}, nil
+	}
+
+	s := NewServer("test", "1.0")
+	s.AddResourceTemplate(template, "files", func(_ context.Context, uri *url.URL, params map[string]string) (ResourceContent, error) {
+		return ResourceContent{URI: uri.String(), Text: params["name"]}, nil
+	})
+	if calls != 0 {
+		t.Fatalf("lister called %d times at registration", calls)
+	}
+	c := newTestClient(t, s)
+
+	listed := make(map[string]bool)
+	for _, r := range c.listResources() {
+		listed[r.URI] = true
+	}
+	for _, uri := range []string{"files://a", "files://b", "files://c"} {
+		if !listed[uri] {
+			t.Errorf("%s not listed", uri)
+		}
+		if content := c.readResource(uri); content.Text != uri[len("files://"):] {
+			t.Errorf("reading %s: got %q", uri, content.Text)
+		}
+	}
+
+	// Every list request asks the lister again
+	c.listResources()
+	if calls != 2 {
+		t.Errorf("lister called %d times for two lists, want 2", calls)
+	}
+}
+
+func TestResourceSize(t *testing.T) {
+	s := NewServer("test", "1.0")
+	s.AddResource("data://big", "big", "", "application/octet-stream", textResource(""), WithResourceSize(1<<30))
+	s.AddResource("data://small", "small", "", "text/plain", textResource(""))
+	c := newTestClient(t, s)
+
+	var result struct {
+		Resources []map[string]interface{}
+	}
+	c.result("resources/list", nil, &result)
+	for _, r := range result.Resources {
+		size, ok := r["size"]
+		switch r["name"] {
+		case "big":
+			if size != float64(1<<30) {
+				t.Errorf("big size = %v, want %d", size, 1<<30)
+			}
+		case "small":
+			if ok {
+				t.Errorf("small has size %v, want it omitted", size)
+			}
+		}
+	}
+}
+
+func TestResourceContentNameAndTitle(t *testing.T) {
+	s := NewServer("test", "1.0")
+	s.AddResource("docs://titled", "titled", "", "text/plain", func(_ context.Context, uri *url.URL) (ResourceContent, error) {
+		return ResourceContent{URI: uri.String(), Name: "guide.md", Title: "User Guide", Text: "hi"}, nil
+	})
+	s.AddResource("docs://plain", "plain", "", "text/plain", textResource("hi"))
+	c := newTestClient(t, s)
+
+	if content := c.readResource("docs://titled"); content.Name != "guide.md" || content.Title != "User Guide" {
+		t.Errorf("titled content = %+v", content)
+	}
+
+	var result struct {
+		Contents []map[string]interface{}
+	}
+	c.result("resources/read", map[string]interface{}{"uri": "docs://plain"}, &result)
+	for _, key := range []string{"name", "title"} {
+		if _, ok := result.Contents[0][key]; ok {
+			t.Errorf("untitled content has %q, want it omitted", key)
+		}
+	}
+}
+
+func TestResourceNotFound(t *testing.T) {
+	template, err := NewResourceTemplate("users://{id}", "users", "application/json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewServer("test", "1.0")
+	s.AddResourceTemplate(template, "user", func(_ context.Context, uri *url.URL, params map[string]string) (ResourceContent, error) {
+		switch params["id"] {
+		case "1":
+			return ResourceContent{URI: uri.String(), Text: "alice"}, nil
+		case "broken":
+			return ResourceContent{}, errTest
+		}
+		return ResourceContent{}, fmt.Errorf("user %s: %w", params["id"], ErrResourceNotFound)
+	})
+	c := newTestClient(t, s)
+
+	read := func(uri string) map[string]interface{} {
+		return map[string]interface{}{"uri": uri}
+	}
+	if got := c.readResource("users://1").Text; got != "alice" {
+		t.Errorf("users://1 = %q", got)
+	}
+	if e := c.callError("resources/read", read("users://2"), -32002); !strings.Contains(e.Message, "user 2") {
+		t.Errorf("handler-reported not found: message %q", e.Message)
+	}
+	c.callError("resources/read", read("users://broken"), -32603)
+	c.callError("resources/read", read("groups://1"), -32602)
+}
+
+func TestResourcePrefix(t *testing.T) {
+	prefixHandler := func(name string) ResourceHandler {
+		return func(_ context.Context, uri *url.URL) (ResourceContent, error) {
+			return ResourceContent{URI: uri.String(), Text: name + " " + uri.String()}, nil
+		}
+	}
+
+	s := NewServer("test", "1.0")
+	s.AddResourcePrefix("db://", prefixHandler("db"))
+	s.AddResourcePrefix("db://admin/", prefixHandler("admin"))
+	s.AddResource("db://config", "config", "", "text/plain", textResource("static"))
+	c := newTestClient(t, s)
+
+	tests := map[string]string{
+		"db://users/42":    "db db://users/42",
+		"db://admin/roles": "admin db://admin/roles",
+		"db://config":      "static",
+	}
+	for uri, want := range tests {
+		if got := c.readResource(uri).Text; got != want {
+			t.Errorf("reading %s: got %q, want %q", uri, got, want)
+		}
+	}
+	c.callError("resources/read", map[string]interface{}{"uri": "file:///x"}, -32602)
+
+	if resources := c.listResources(); len(resources) != 1 {
+		t.Errorf("listed %+v, want only the static resource", resources)
+	}
+}
+
+func TestParamPattern(t *testing.T) {
+	template, err := NewResourceTemplate("files://{id}", "files", "text/plain", WithParamPattern("id", `\d+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for uri, want := range map[string]bool{
+		"files://42":   true,
+		"files://abc":  false,
+		"files://4a2":  false,
+		"files://":     false,
+		"files://42/x": false,
+	} {
+		if _, ok := template.Match(uri); ok != want {
+			t.Errorf("Match(%q) = %v, want %v", uri, ok, want)
+		}
+	}
+
+	// Groups inside a pattern don't shift the parameters after it
+	multi, err := NewResourceTemplate("repo://{owner}/{name}", "", "", WithParamPattern("owner", `(org|user)-\w+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, ok := multi.Match("repo://org-go/tools")
+	if !ok || params["owner"] != "org-go" || params["name"] != "tools" {
+		t.Errorf("Match = %v, %v", params, ok)
+	}
+
+	s := NewServer("test", "1.0")
+	var reached []string
+	s.AddResourceTemplate(template, "file", func(_ context.Context, uri *url.URL, params map[string]string) (ResourceContent, error) {
+		reached = append(reached, params["id"])
+		return ResourceContent{URI: uri.String(), Text: params["id"]}, nil
+	})
+	c := newTestClient(t, s)
+
+	if got := c.readResource("files://42").Text; got != "42" {
+		t.Errorf("files://42 = %q", got)
+	}
+	c.callError("resources/read", map[string]interface{}{"uri": "files://abc"}, -32602)
+	if len(reached) != 1 {
+		t.Errorf("handler reached for %v", reached)
+	}
+
+	if _, err := NewResourceTemplate("files://{id}", "", "", WithParamPattern("id", `(`)); err == nil {
+		t.Error("accepted an invalid pattern")
+	}
+}
+
+func TestConditionalRead(t *testing.T) {
+	version := "v1"
+	s := NewServer("test", "1.0")
+	s.AddResource("docs://page", "page", "", "text/plain", func(_ context.Context, uri *url.URL) (ResourceContent, error) {
+		return ResourceContent{
+			URI:          uri.String(),
+			Text:         "content " + version,
+			ETag:         `"` + version + `"`,
+			LastModified: "2025-01-02T03:04:05Z",
+		}, nil
+	})
+	c := newTestClient(t, s)
+
+	read := func(ifNoneMatch string) readResult {
+		params := map[string]interface{}{"uri": "docs://page"}
+		if ifNoneMatch != "" {
+			params["_meta"] = map[string]interface{}{"ifNoneMatch": ifNoneMatch}
+		}
+		var result readResult
+		c.result("resources/read", params, &result)
+		return result
+	}
+
+	first := read("")
+	if len(first.Contents) != 1 || first.Meta.NotModified {
+		t.Fatalf("first read = %+v", first)
+	}
+	etag := first.Contents[0].ETag
+	if etag != `"v1"` || first.Contents[0].LastModified != "2025-01-02T03:04:05Z" {
+		t.Errorf("first read metadata = %+v", first.Contents[0])
+	}
+
+	if again := read(etag); !again.Meta.NotModified || len(again.Contents) != 0 {
+		t.Errorf("read with a current etag = %+v, want notModified", again)
+	}
+
+	if stale := read(`"v0"`); stale.Meta.NotModified || stale.Contents[0].Text != "content v1" {
+		t.Errorf("read with a stale etag = %+v", stale)
+	}
+}
+
+func TestResourceProvider(t *testing.T) {
+	var generation int
+	s := NewServer("test", "1.0")
+	s.AddResource("static://a", "a", "", "text/plain", textResource("static"))
+	s.SetResourceProvider(func(context.Context) ([]Resource, error) {
+		generation++
+		if generation > 2 {
+			return nil, errTest
+		}
+		return []Resource{{URI: fmt.Sprintf("db://row/%d", generation), Name: fmt.Sprint(generation)}}, nil
+	})
+	s.SetResourceReader(func(_ context.Context, uri *url.URL) (ResourceContent, error) {
+		if uri.Host != "row" {
+			return ResourceContent{}, ErrResourceNotFound
+		}
+		return ResourceContent{URI: uri.String(), Text: "row " + uri.Path}, nil
+	})
+	c := newTestClient(t, s)
+
+	uris := func() []string {
+		var uris []string
+		for _, r := range c.listResources() {
+			uris = append(uris, r.URI)
+		}
+		return uris
+	}
+	if got := uris(); !slices.Equal(got, []string{"static://a", "db://row/1"}) {
+		t.Errorf("first list = %v", got)
+	}
+	if got := uris(); !slices.Equal(got, []string{"static://a", "db://row/2"}) {
+		t.Errorf("second list = %v", got)
+	}
+	c.callError("resources/list", nil, -32603)
+
+	if got := c.readResource("db://row/7").Text; got != "row /7" {
+		t.Errorf("provided resource = %q", got)
+	}
+	if got := c.readResource("static://a").Text; got != "static" {
+		t.Errorf("static resource = %q", got)
+	}
+	c.callError("resources/read", map[string]interface{}{"uri": "db://other"}, -32002)
+}
+
+func TestMultiSegmentParam(t *testing.T) {
+	for _, tmpl := range []string{"files://{path...}", "files://{+path}"} {
+		template, err := NewResourceTemplate(tmpl, "files", "text/plain")
+		if err != nil {
+			t.Fatal(err)
+		}
+		params, ok := template.Match("files://a/b/c.txt")
+		if !ok || params["path"] != "a/b/c.txt" {
+			t.Errorf("%s: Match = %v, %v", tmpl, params, ok)
+		}
+	}
+
+	// Single-segment parameters still stop at a slash
+	single, err := NewResourceTemplate("files://{path}", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params, ok := single.Match("files://a/b/c.txt"); ok {
+		t.Errorf("{path} matched across slashes: %v", params)
+	}
+
+	mixed, err := NewResourceTemplate("repo://{owner}/{path...}/raw", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, ok := mixed.Match("repo://go/src/net/http/raw")
+	if !ok || params["owner"] != "go" || params["path"] != "src/net/http" {
+		t.Errorf("mixed: Match = %v, %v", params, ok)
+	}
+
+	s := NewServer("test", "1.0")
+	template, err := NewResourceTemplate("files://{path...}", "files", "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddResourceTemplate(template, "file", func(_ context.Context, uri *url.URL, params map[string]string) (ResourceContent, error) {
+		return ResourceContent{URI: uri.String(), Text: params["path"]}, nil
+	})
+	c := newTestClient(t, s)
+
+	if got := c.readResource("files://a/b/c.txt").Text; got != "a/b/c.txt" {
+		t.Errorf("read files://a/b/c.txt = %q", got)
+	}
+}
+
+func TestURIAlias(t *testing.T) {
+	s := NewServer("test", "1.0")
+	s.AddResource("documents://guide", "guide", "", "text/plain", textResource("guide"))
+	s.AddResource("documents://api/v2", "api", "", "text/plain", textResource("api v2"))
+	s.AddResource("documents://api/v1", "api", "", "text/plain", textResource("api v1"))
+	s.AddResource("archive://legacy", "legacy", "", "text/plain", textResource("legacy"))
+	s.AddURIAlias("docs://api", "documents://api/v2")
+	s.AddURIPrefixAlias("docs://", "documents://")
+	s.AddURIPrefixAlias("docs://old/", "archive://")
+	c := newTestClient(t, s)
+
+	for uri, want := range map[string]string{
+		"documents://guide": "guide",
+		"docs://guide":      "guide",
+		"docs://api":        "api v2",
+		"docs://api/v1":     "api v1",
+		"docs://old/legacy": "legacy",
+	} {
+		if got := c.readResource(uri).Text; got != want {
+			t.Errorf("read %s = %q, want %q", uri, got, want)
+		}
+	}
+
+	c.callError("resources/read", map[string]interface{}{"uri": "docs://missing"}, -32602)
+}
+
+func TestNegotiateMIMEType(t *testing.T) {
+	s := NewServer("test", "1.0")
+	s.AddResource("data://report", "report", "", "application/json", func(ctx context.Context, uri *url.URL) (ResourceContent, error) {
+		switch mimeType := NegotiateMIMEType(ctx, "application/json", "text/csv"); mimeType {
+		case "text/csv":
+			return ResourceContent{URI: uri.String(), MIMEType: mimeType, Text: "name,count\nalpha,1\n"}, nil
+		default:
+			return ResourceContent{URI: uri.String(), MIMEType: mimeType, Text: `[{"name":"alpha","count":1}]`}, nil
+		}
+	})
+	c := newTestClient(t, s)
+
+	for _, tt := range []struct {
+		accept []string
+		want   string
+	}{
+		{nil, "application/json"},
+		{[]string{"text/csv"}, "text/csv"},
+		{[]string{"TEXT/CSV"}, "text/csv"},
+		{[]string{"text/*"}, "text/csv"},
+		{[]string{"text/csv", "application/json"}, "text/csv"},
+		{[]string{"application/json", "text/csv"}, "application/json"},
+		{[]string{"image/png", "*/*"}, "application/json"},
+		{[]string{"image/png"}, "application/json"},
+	} {
+		params := map[string]interface{}{"uri": "data://report"}
+		if tt.accept != nil {
+			params["accept"] = tt.accept
+		}
+		var result readResult
+		c.result("resources/read", params, &result)
+		if len(result.Contents) != 1 || result.Contents[0].MIMEType != tt.want {
+			t.Errorf("accept %q: got %+v, want %s", tt.accept, result.Contents, tt.want)
+		}
+	}
+
+	if got := c.readResource("data://report").Text; !strings.HasPrefix(got, "[") {
+		t.Errorf("default representation = %q", got)
+	}
+	if got := NegotiateMIMEType(context.Background()); got != "" {
+		t.Errorf("NegotiateMIMEType with nothing available = %q", got)
+	}
+}
+
+func TestOverlappingTemplates(t *testing.T) {
+	s := NewServer("test", "1.0")
+	add := func(tmpl, label string) {
+		template, err := NewResourceTemplate(tmpl, "", "text/plain")
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.AddResourceTemplate(template, label, func(_ context.Context, uri *url.URL, params map[string]string) (ResourceContent, error) {
+			return ResourceContent{URI: uri.String(), Text: label + ":" + params["name"] + params["path"]}, nil
+		})
+	}
+	// The narrower template comes first, so it wins where both match
+	add("files://readme/{name}", "readme")
+	add("files://{path...}", "any")
+	c := newTestClient(t, s)
+
+	// Repeat reads, as map order would vary between them
+	for i := 0; i < 20; i++ {
+		if got := c.readResource("files://readme/intro").Text; got != "readme:intro" {
+			t.Fatalf("files://readme/intro read through %q", got)
+		}
+		if got := c.readResource("files://src/main.go").Text; got != "any:src/main.go" {
+			t.Fatalf("files://src/main.go read through %q", got)
+		}
+	}
+}
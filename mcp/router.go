@@ -0,0 +1,71 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// Router serves several MCP servers from one http.Handler, each at its own
+// path with its own HTTPTransport, so that they can share one http.Server:
+//
+//	router := mcp.NewRouter()
+//	router.Handle(ctx, "/mcp/calculator", calculator)
+//	router.Handle(ctx, "/mcp/files", files)
+//	http.ListenAndServe(":8080", router)
+//
+// As each path has a single HTTPTransport, each serves a single client
+// session.
+type Router struct {
+	mux *http.ServeMux
+
+	mu      sync.Mutex
+	servers []*MCPServer
+}
+
+// NewRouter creates an empty router
+func NewRouter() *Router {
+	return &Router{mux: http.NewServeMux()}
+}
+
+// Handle connects server to a new HTTPTransport and serves it at path.
+// Handlers run with a context derived from ctx. Like http.ServeMux.Handle,
+// it panics if path is invalid or already has a server, in which case
+// server is left unconnected. A server can be mounted only once.
+func (r *Router) Handle(ctx context.Context, path string, server *MCPServer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if slices.Contains(r.servers, server) {
+		return errors.New("mcp: server already mounted")
+	}
+
+	// Register the path first, so that a bad one panics before the server
+	// starts reading from the transport
+	transport := NewHTTPTransport()
+	r.mux.Handle(path, transport)
+	r.servers = append(r.servers, server)
+
+	return server.server.Connect(ctx, transport)
+}
+
+// ServeHTTP dispatches the request to the server mounted at its path
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mux.ServeHTTP(w, req)
+}
+
+// Close closes every server the router has connected
+func (r *Router) Close() error {
+	r.mu.Lock()
+	servers := r.servers
+	r.servers = nil
+	r.mu.Unlock()
+
+	var errs []error
+	for _, server := range servers {
+		errs = append(errs, server.Close())
+	}
+	return errors.Join(errs...)
+}
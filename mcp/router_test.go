@@ -0,0 +1,86 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	router := NewRouter()
+	defer router.Close()
+
+	for _, name := range []string{"calculator", "files"} {
+		server := NewMCPServer(name, "1.0")
+		server.Tool("whoami", "", nil, func(context.Context, map[string]interface{}) (string, error) {
+			return name, nil
+		})
+		if err := router.Handle(context.Background(), "/mcp/"+name, server); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	for _, name := range []string{"calculator", "files"} {
+		url := ts.URL + "/mcp/" + name
+
+		_, msg := postMessage(t, url, httpInitialize)
+		var init struct{ ServerInfo ServerInfo }
+		if err := json.Unmarshal(msg.Result, &init); err != nil || init.ServerInfo.Name != name {
+			t.Errorf("%s: initialize answered by %+v", url, msg)
+		}
+
+		_, msg = postMessage(t, url, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"whoami"}}`)
+		var result toolResult
+		if err := json.Unmarshal(msg.Result, &result); err != nil || result.text() != name {
+			t.Errorf("%s: tools/call answered %s", url, msg.Result)
+		}
+	}
+
+	if status, _ := postMessage(t, ts.URL+"/mcp/other", httpInitialize); status != http.StatusNotFound {
+		t.Errorf("unmounted path: status %d, want 404", status)
+	}
+}
+
+func TestRouterRejectsBadMounts(t *testing.T) {
+	router := NewRouter()
+	defer router.Close()
+
+	first := NewMCPServer("first", "1.0")
+	if err := router.Handle(context.Background(), "/mcp", first); err != nil {
+		t.Fatal(err)
+	}
+	if err := router.Handle(context.Background(), "/mcp/again", first); err == nil {
+		t.Error("mounting a server twice succeeded")
+	}
+
+	// A duplicate path panics before the server is connected
+	second := NewMCPServer("second", "1.0")
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("duplicate path did not panic")
+			}
+		}()
+		router.Handle(context.Background(), "/mcp", second)
+	}()
+	if _, err := second.Server().getTransport(); err == nil {
+		t.Error("server connected despite the duplicate path")
+	}
+
+	// The first mount still serves
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+	_, msg := postMessage(t, ts.URL+"/mcp", httpInitialize)
+	var init struct{ ServerInfo ServerInfo }
+	if err := json.Unmarshal(msg.Result, &init); err != nil || init.ServerInfo.Name != "first" {
+		t.Errorf("initialize answered by %+v", msg)
+	}
+	if status, _ := postMessage(t, ts.URL+"/mcp/again", httpInitialize); status != http.StatusNotFound {
+		t.Errorf("rejected mount: status %d, want 404", status)
+	}
+}
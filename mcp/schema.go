@@ -0,0 +1,118 @@
+package mcp
+
+import (
+	"encoding/json"
+)
+
+// Schema is a JSON Schema under construction. Start with one of Object,
+// String, Number, Integer, Boolean or Array and chain methods to refine it:
+//
+//	schema := mcp.Object().
+//		Prop("a", mcp.Number().Desc("First operand")).
+//		Prop("op", mcp.String().Enum("add", "subtract")).
+//		Required("a", "op").
+//		Build()
+//
+// The result is a draft-07 schema suitable for a tool's input schema.
+type Schema struct {
+	typ         string
+	description string
+	properties  map[string]*Schema
+	required    []string
+	items       *Schema
+	enum        []interface{}
+}
+
+// Object starts a schema for a JSON object
+func Object() *Schema {
+	return &Schema{typ: "object", properties: make(map[string]*Schema)}
+}
+
+// String starts a schema for a JSON string
+func String() *Schema {
+	return &Schema{typ: "string"}
+}
+
+// Number starts a schema for any JSON number
+func Number() *Schema {
+	return &Schema{typ: "number"}
+}
+
+// Integer starts a schema for a JSON number without a fractional part
+func Integer() *Schema {
+	return &Schema{typ: "integer"}
+}
+
+// Boolean starts a schema for true or false
+func Boolean() *Schema {
+	return &Schema{typ: "boolean"}
+}
+
+// Array starts a schema for a JSON array whose elements match items. A nil
+// items allows any elements.
+func Array(items *Schema) *Schema {
+	return &Schema{typ: "array", items: items}
+}
+
+// Desc sets the schema's description
+func (s *Schema) Desc(description string) *Schema {
+	s.description = description
+	return s
+}
+
+// Prop adds a property to an object schema
+func (s *Schema) Prop(name string, prop *Schema) *Schema {
+	if s.properties == nil {
+		s.properties = make(map[string]*Schema)
+	}
+	s.properties[name] = prop
+	return s
+}
+
+// Required marks properties of an object schema as required
+func (s *Schema) Required(names ...string) *Schema {
+	s.required = append(s.required, names...)
+	return s
+}
+
+// Enum restricts the schema to the given values
+func (s *Schema) Enum(values ...interface{}) *Schema {
+	s.enum = append(s.enum, values...)
+	return s
+}
+
+// MarshalJSON encodes the schema as JSON Schema
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	out := map[string]interface{}{"type": s.typ}
+	if s.description != "" {
+		out["description"] = s.description
+	}
+	if s.typ == "object" {
+		properties := s.properties
+		if properties == nil {
+			properties = map[string]*Schema{}
+		}
+		out["properties"] = properties
+	}
+	if len(s.required) > 0 {
+		out["required"] = s.required
+	}
+	if s.items != nil {
+		out["items"] = s.items
+	}
+	if len(s.enum) > 0 {
+		out["enum"] = s.enum
+	}
+
+	return marshalJSON(out)
+}
+
+// Build returns the schema as JSON, ready to pass as a tool's input schema
+func (s *Schema) Build() json.RawMessage {
+	data, err := s.MarshalJSON()
+	if err != nil {
+		// Only values passed to Enum can fail to encode
+		panic("mcp: encoding schema: " + err.Error())
+	}
+	return data
+}
@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSchemaBuilder(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema *Schema
+		want   string
+	}{
+		{"string", String(), `{"type":"string"}`},
+		{"number", Number().Desc("First operand"), `{"description":"First operand","type":"number"}`},
+		{"integer enum", Integer().Enum(1, 2, 3), `{"enum":[1,2,3],"type":"integer"}`},
+		{"boolean", Boolean(), `{"type":"boolean"}`},
+		{"empty object", Object(), `{"properties":{},"type":"object"}`},
+		{"any array", Array(nil), `{"type":"array"}`},
+		{"array", Array(String().Enum("a", "b")), `{"items":{"enum":["a","b"],"type":"string"},"type":"array"}`},
+		{
+			"calculator",
+			Object().
+				Prop("a", Number().Desc("First operand")).
+				Prop("b", Number()).
+				Prop("op", String().Enum("add", "subtract")).
+				Required("a", "b", "op"),
+			`{"properties":{"a":{"description":"First operand","type":"number"},"b":{"type":"number"},"op":{"enum":["add","subtract"],"type":"string"}},"required":["a","b","op"],"type":"object"}`,
+		},
+		{
+			"nested",
+			Object().Prop("filter", Object().Prop("tags", Array(String()))),
+			`{"properties":{"filter":{"properties":{"tags":{"items":{"type":"string"},"type":"array"}},"type":"object"}},"type":"object"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		got := tt.schema.Build()
+		if string(got) != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, got, tt.want)
+		}
+
+		// A schema embedded in another value encodes the same way
+		embedded, err := json.Marshal(map[string]*Schema{"s": tt.schema})
+		if err != nil || string(embedded) != `{"s":`+tt.want+`}` {
+			t.Errorf("%s embedded: got %s, %v", tt.name, embedded, err)
+		}
+	}
+}
+
+func TestSchemaBuilderPanicsOnBadEnum(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Build did not panic on an unencodable enum value")
+		}
+	}()
+	String().Enum(func() {}).Build()
+}
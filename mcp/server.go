@@ -4,44 +4,128 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"runtime/debug"
+	"strconv"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
+// ErrSendQueueFull is returned when sending a notification while the number
+// of messages waiting on the transport is at the limit set by WithSendLimit
+var ErrSendQueueFull = errors.New("mcp: send queue full")
+
+// ErrNotConnected is returned when sending a message before Connect
+var ErrNotConnected = errors.New("mcp: server not connected")
+
+// ErrNotInitialized is returned when sending a request other than ping
+// before the client has sent notifications/initialized
+var ErrNotInitialized = errors.New("mcp: client not initialized")
+
 // Server represents an MCP server
 type Server struct {
 	// Server identity
 	info ServerInfo
 
 	// Capabilities
-	capabilities map[string]interface{}
+	capabilities       map[string]interface{}
+	clientCapabilities map[string]interface{}
+	negotiatedVersion  string
 
 	// Resources
 	resources                []Resource
 	resourceHandlers         map[string]ResourceHandler
 	resourceTemplates        map[string]*ResourceTemplate
+	resourceTemplateOrder    []string // templates in registration order, for matching
 	resourceTemplateHandlers map[string]ResourceTemplateHandler
+	resourceStreams          map[string]resourceStream
+	resourcePrefixes         []resourcePrefix
+	uriAliases               map[string]string
+	uriPrefixAliases         []uriPrefixAlias
+	resourceProvider         ResourceLister
+	resourceReader           ResourceHandler
 
 	// Tools
-	tools        []Tool
-	toolHandlers map[string]ToolHandler
+	tools         []Tool
+	toolHandlers  map[string]ToolHandler
+	toolDecoders  map[string]ArgumentDecoder
+	toolArgLimits map[string]int
+	toolRequires  map[string][]string
+	toolsVersion  uint64          // bumped whenever a tool is registered
+	toolsList     json.RawMessage // cached unfiltered tools/list result
+	toolCallHook  ToolCallHook
 
 	// Prompts
 	prompts        []Prompt
 	promptHandlers map[string]PromptHandler
 
 	// Transport
-	transport Transport
+	transport   Transport
+	transportMu sync.RWMutex
+	connCtx     context.Context
+	connCancel  context.CancelFunc
+	done        chan struct{}
+	stopped     chan struct{} // closed when Connect's read loop returns
+	loopErr     error
+	manual      bool
+	idleTimer   *time.Timer
+
+	// Options
+	protocolVersion        string // pinned by WithProtocolVersion
+	initializeTimeout      time.Duration
+	idleTimeout            time.Duration
+	baseURL                string
+	requestTimeout         time.Duration
+	readOnly               bool
+	coerceArguments        bool
+	serialMethods          map[string]bool
+	idGenerator            func() json.RawMessage
+	sendSlots              chan struct{}
+	errorLogging           bool
+	debugErrors            bool
+	negotiatePromptContent bool
+	auditLogger            AuditLogger
+	maxToolOutputBytes     int
+	strictToolResults      bool
+	toolResultCache        *toolResultCache
+
+	// Method dispatch
+	methods map[string]MethodHandler
+
+	// Hooks
+	initializeHook  InitializeHook
+	disconnectHook  func()
+	sendHook        SendHook
+	fallbackHandler FallbackHandler
+
+	// Logging
+	minLogSeverity atomic.Int32 // set by logging/setLevel
 
 	// State
-	initialized atomic.Bool
+	initialized atomic.Bool // initialize request handled
+	ready       atomic.Bool // initialized notification received
+	draining    atomic.Bool
 	nextID      int64
 	mu          sync.RWMutex
+
+	// In-flight request tracking
+	inflightMu     sync.Mutex
+	inflight       int
+	idle           chan struct{}
+	requestCancels map[string]context.CancelFunc
+
+	// Traffic counters reported by Stats
+	stats serverStats
+
+	// Server-initiated requests awaiting a response
+	pendingMu sync.Mutex
+	pending   map[string]chan *Message
 }
 
 // NewServer creates a new MCP server
-func NewServer(name, version string) *Server {
-	return &Server{
+func NewServer(name, version string, opts ...ServerOption) *Server {
+	s := &Server{
 		info: ServerInfo{
 			Name:    name,
 			Version: version,
@@ -56,46 +140,518 @@ func NewServer(name, version string) *Server {
 		resourceHandlers:         make(map[string]ResourceHandler),
 		resourceTemplates:        make(map[string]*ResourceTemplate),
 		resourceTemplateHandlers: make(map[string]ResourceTemplateHandler),
+		resourceStreams:          make(map[string]resourceStream),
 		tools:                    make([]Tool, 0),
 		toolHandlers:             make(map[string]ToolHandler),
+		toolDecoders:             make(map[string]ArgumentDecoder),
+		toolArgLimits:            make(map[string]int),
+		toolRequires:             make(map[string][]string),
 		prompts:                  make([]Prompt, 0),
 		promptHandlers:           make(map[string]PromptHandler),
 	}
+	s.methods = s.builtinMethods()
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
-// Connect attaches a transport to the server
+// Connect attaches a transport to the server. Handlers run with a context
+// derived from ctx that is cancelled when the connection closes.
 func (s *Server) Connect(ctx context.Context, transport Transport) error {
-	s.transport = transport
+	connCtx, cancel, done := s.attach(ctx, transport, false)
+
+	stopped := make(chan struct{})
+	s.transportMu.Lock()
+	s.stopped = stopped
+	s.transportMu.Unlock()
 
 	// Start the message handler
-	go s.handleMessages(ctx)
+	go func() {
+		defer close(done)
+		defer s.disconnected()
+		defer cancel()
+		s.setLoopErr(s.handleMessages(connCtx, transport))
+		close(stopped)
+	}()
 
 	return nil
 }
 
-// Close terminates the server connection
-func (s *Server) Close() error {
-	if s.transport != nil {
-		return s.transport.Close()
+// attach records transport as the server's connection and returns the
+// connection context, its cancel function and the channel to close when the
+// message loop exits
+func (s *Server) attach(ctx context.Context, transport Transport, manual bool) (context.Context, context.CancelFunc, chan struct{}) {
+	sess := &session{transport: transport}
+	connCtx, cancel := context.WithCancel(withSession(withTransportKind(ctx, transport), sess))
+	sess.done = connCtx.Done()
+
+	done := make(chan struct{})
+
+	s.transportMu.Lock()
+	s.transport = transport
+	s.connCtx = connCtx
+	s.connCancel = cancel
+	s.done = done
+	s.loopErr = nil
+	s.manual = manual
+	s.stopped = nil
+	s.idleTimer = nil
+	s.transportMu.Unlock()
+
+	// Each connection negotiates afresh
+	s.initialized.Store(false)
+	s.ready.Store(false)
+	s.minLogSeverity.Store(0)
+	s.mu.Lock()
+	s.clientCapabilities = nil
+	s.negotiatedVersion = ""
+	s.mu.Unlock()
+
+	// Drop clients that never initialize
+	if s.initializeTimeout > 0 {
+		timer := time.AfterFunc(s.initializeTimeout, func() {
+			if !s.initialized.Load() {
+				s.Close()
+			}
+		})
+		go func() {
+			<-connCtx.Done()
+			timer.Stop()
+		}()
 	}
+
+	// Drop connections that go quiet
+	if s.idleTimeout > 0 {
+		timer := time.AfterFunc(s.idleTimeout, func() {
+			s.Close()
+		})
+		s.transportMu.Lock()
+		s.idleTimer = timer
+		s.transportMu.Unlock()
+		go func() {
+			<-connCtx.Done()
+			timer.Stop()
+		}()
+	}
+
+	return connCtx, cancel, done
+}
+
+// resetIdleTimer restarts the idle timeout after an inbound message
+func (s *Server) resetIdleTimer() {
+	s.transportMu.RLock()
+	timer := s.idleTimer
+	s.transportMu.RUnlock()
+
+	if timer != nil {
+		timer.Reset(s.idleTimeout)
+	}
+}
+
+// setLoopErr records why the message loop exited
+func (s *Server) setLoopErr(err error) {
+	s.transportMu.Lock()
+	defer s.transportMu.Unlock()
+
+	s.loopErr = err
+}
+
+// Done returns a channel that is closed when the message loop started by
+// Connect, or run by Serve, exits, whether because the transport closed or
+// failed, the server was shut down, or the connection's context was
+// cancelled. It returns nil before Connect is called.
+func (s *Server) Done() <-chan struct{} {
+	s.transportMu.RLock()
+	defer s.transportMu.RUnlock()
+
+	return s.done
+}
+
+// Err reports why the message loop exited: io.EOF when the client closed the
+// connection, ErrTransportClosed when the transport was closed, the context's
+// error when it was cancelled, or the transport's error when reading failed.
+// It returns nil while the loop is still running.
+func (s *Server) Err() error {
+	s.transportMu.RLock()
+	defer s.transportMu.RUnlock()
+
+	return s.loopErr
+}
+
+// InitializeHook receives the client's initialize params exactly as sent,
+// including experimental or unknown fields the server does not parse
+type InitializeHook func(ctx context.Context, params json.RawMessage)
+
+// OnInitialize sets a hook that is called when the client initializes, before
+// the initialize response is sent
+func (s *Server) OnInitialize(hook InitializeHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.initializeHook = hook
+}
+
+// OnDisconnect sets a hook that is called once the connection has closed and
+// the server has stopped reading messages
+func (s *Server) OnDisconnect(hook func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.disconnectHook = hook
+}
+
+// disconnected runs the disconnect hook, if any
+func (s *Server) disconnected() {
+	s.mu.RLock()
+	hook := s.disconnectHook
+	s.mu.RUnlock()
+
+	if hook != nil {
+		hook()
+	}
+}
+
+// SendHook inspects or rewrites an outbound message before it is written
+// to the transport. Returning an error, or a nil message, drops the message.
+// Errors are reported like other internal failures; see WithErrorLogging.
+type SendHook func(msg *Message) (*Message, error)
+
+// OnSend sets a hook that every outbound message passes through: responses,
+// errors, notifications and server-initiated requests
+func (s *Server) OnSend(hook SendHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sendHook = hook
+}
+
+// send transmits a message to the client through the send hook. Every
+// outbound message goes through send.
+func (s *Server) send(ctx context.Context, msg *Message) error {
+	return s.sendWithLimit(ctx, msg, msg.ID != nil)
+}
+
+// sendStreamed is send for the messages of a streamed resource. Chunk
+// notifications must not be dropped, so they wait for room under
+// WithSendLimit like responses do.
+func (s *Server) sendStreamed(ctx context.Context, msg *Message) error {
+	return s.sendWithLimit(ctx, msg, true)
+}
+
+// sendWithLimit implements send. If wait is false and the send limit is
+// reached, it fails with ErrSendQueueFull instead of waiting.
+func (s *Server) sendWithLimit(ctx context.Context, msg *Message, wait bool) error {
+	transport, err := s.getTransport()
+	if err != nil {
+		return err
+	}
+
+	return s.sendOn(ctx, transport, msg, wait)
+}
+
+// sendOn implements sendWithLimit for a given transport
+func (s *Server) sendOn(ctx context.Context, transport Transport, msg *Message, wait bool) error {
+	s.mu.RLock()
+	hook := s.sendHook
+	s.mu.RUnlock()
+
+	if hook != nil {
+		original := msg
+		var err error
+		if msg, err = hook(msg); err != nil {
+			// Responses are reported by their senders. Log messages are
+			// not, since the report would be a log message going through
+			// the same hook.
+			if original.Method != "" && original.Method != "notifications/message" {
+				s.reportError(ctx, fmt.Errorf("send hook dropped %s: %w", describeMessage(original), err))
+			}
+			return err
+		}
+		if msg == nil {
+			return nil
+		}
+	}
+
+	// Bound how many messages may wait on a slow client. Notifications
+	// fail fast so that chatty handlers notice and back off; responses and
+	// requests wait their turn.
+	if s.sendSlots != nil {
+		if !wait {
+			select {
+			case s.sendSlots <- struct{}{}:
+			default:
+				return ErrSendQueueFull
+			}
+		} else {
+			select {
+			case s.sendSlots <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		defer func() { <-s.sendSlots }()
+	}
+
+	if err := transport.Send(ctx, msg); err != nil {
+		return err
+	}
+
+	s.recordSent(msg)
 	return nil
 }
 
+// PendingSends returns how many outbound messages are waiting on the
+// transport. It is only tracked when WithSendLimit is set and is zero
+// otherwise.
+func (s *Server) PendingSends() int {
+	return len(s.sendSlots)
+}
+
+// notify sends a notification to the client. A nil params is omitted.
+func (s *Server) notify(ctx context.Context, method string, params interface{}) error {
+	notification, err := newNotification(method, params)
+	if err != nil {
+		return err
+	}
+
+	return s.send(ctx, notification)
+}
+
+// notifySession sends a notification on the connection of sess, failing if
+// that connection has ended
+func (s *Server) notifySession(ctx context.Context, sess *session, method string, params interface{}) error {
+	select {
+	case <-sess.done:
+		return ErrTransportClosed
+	default:
+	}
+
+	notification, err := newNotification(method, params)
+	if err != nil {
+		return err
+	}
+
+	return s.sendOn(ctx, sess.transport, notification, false)
+}
+
+// newNotification builds a notification message. A nil params is omitted.
+func newNotification(method string, params interface{}) (*Message, error) {
+	notification := &Message{
+		JSONRPC: "2.0",
+		Method:  method,
+	}
+
+	if params != nil {
+		paramsBytes, err := marshalJSON(params)
+		if err != nil {
+			return nil, err
+		}
+		notification.Params = paramsBytes
+	}
+
+	return notification, nil
+}
+
+// Close terminates the server connection, cancelling the context of any
+// handlers still running. For a server started with Connect it returns once
+// the read loop has stopped; handlers may still be finishing, and the
+// OnDisconnect hook may still be running.
+func (s *Server) Close() error {
+	s.transportMu.RLock()
+	transport, cancel, stopped := s.transport, s.connCancel, s.stopped
+	s.transportMu.RUnlock()
+
+	if transport == nil {
+		return nil
+	}
+
+	cancel()
+	err := transport.Close()
+
+	if stopped != nil {
+		<-stopped
+	}
+
+	return err
+}
+
+// getTransport returns the connected transport, ErrNotConnected if Connect
+// has not been called yet, or ErrTransportClosed once the connection is gone
+func (s *Server) getTransport() (Transport, error) {
+	s.transportMu.RLock()
+	defer s.transportMu.RUnlock()
+
+	if s.transport == nil {
+		return nil, ErrNotConnected
+	}
+	if s.connCtx.Err() != nil {
+		return nil, ErrTransportClosed
+	}
+
+	return s.transport, nil
+}
+
 // handleMessages processes incoming messages
-func (s *Server) handleMessages(ctx context.Context) {
+func (s *Server) handleMessages(ctx context.Context, transport Transport) error {
+	// Requests for serial methods are queued to one worker per method
+	queues := make(map[string]chan *Message)
+	defer func() {
+		for _, queue := range queues {
+			close(queue)
+		}
+	}()
+
 	for {
-		msg, err := s.transport.Receive(ctx)
+		msg, err := transport.Receive(ctx)
 		if err != nil {
-			// Handle error or return if context is done
-			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-				return
+			// Skip a malformed message; any other failure, including the
+			// context ending or the connection closing, stops the loop
+			if !isMessageError(err) {
+				return err
 			}
-			// TODO: Log error
+			s.reportError(ctx, fmt.Errorf("reading message: %w", err))
 			continue
 		}
 
-		go s.handleMessage(ctx, msg)
+		s.resetIdleTimer()
+		s.recordReceived(msg)
+
+		// Responses answer requests the server sent
+		if msg.Method == "" && msg.ID != nil {
+			s.handleResponse(msg)
+			continue
+		}
+
+		s.beginRequest()
+
+		if s.serialMethods[msg.Method] {
+			queue, ok := queues[msg.Method]
+			if !ok {
+				queue = make(chan *Message, serialQueueSize)
+				queues[msg.Method] = queue
+				go s.handleSerial(ctx, queue)
+			}
+			queue <- msg
+			continue
+		}
+
+		go func() {
+			defer s.endRequest()
+			s.handleMessage(ctx, msg)
+		}()
+	}
+}
+
+// isMessageError reports whether a Receive error affects a single malformed
+// message rather than the connection. Any other error, such as an I/O
+// failure, ends the connection, since the transport cannot recover from it.
+func isMessageError(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return errors.Is(err, ErrInvalidMessage) || errors.As(err, &syntaxErr) || errors.As(err, &typeErr)
+}
+
+// serialQueueSize is how many requests for a serial method may wait before
+// the read loop blocks
+const serialQueueSize = 64
+
+// handleSerial handles queued messages one at a time in arrival order
+func (s *Server) handleSerial(ctx context.Context, queue <-chan *Message) {
+	for msg := range queue {
+		s.handleMessage(ctx, msg)
+		s.endRequest()
+	}
+}
+
+// beginRequest records that a message handler has started
+func (s *Server) beginRequest() {
+	s.inflightMu.Lock()
+	defer s.inflightMu.Unlock()
+
+	s.inflight++
+}
+
+// endRequest records that a message handler has finished
+func (s *Server) endRequest() {
+	s.inflightMu.Lock()
+	defer s.inflightMu.Unlock()
+
+	s.inflight--
+	if s.inflight == 0 && s.idle != nil {
+		close(s.idle)
+		s.idle = nil
+	}
+}
+
+// waitIdle blocks until no message handlers are running or ctx is done
+func (s *Server) waitIdle(ctx context.Context) error {
+	s.inflightMu.Lock()
+	if s.inflight == 0 {
+		s.inflightMu.Unlock()
+		return nil
 	}
+	if s.idle == nil {
+		s.idle = make(chan struct{})
+	}
+	idle := s.idle
+	s.inflightMu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Drain stops the server from accepting new tool calls. Afterwards tools/list
+// returns an empty list and tools/call is rejected with a retryable error,
+// while calls already in progress run to completion. Other requests are
+// still served.
+func (s *Server) Drain() {
+	s.draining.Store(true)
+}
+
+// Shutdown drains the server, waits for in-flight requests to finish and
+// then closes the connection. If ctx is done first, the connection is closed
+// anyway and the context's error is returned. Shutdown must not be called
+// from within a handler.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.Drain()
+
+	waitErr := s.waitIdle(ctx)
+	if err := s.Close(); err != nil {
+		return err
+	}
+
+	return waitErr
+}
+
+// NegotiatedVersion returns the protocol version agreed with the client
+// during initialize, or the empty string before initialize
+func (s *Server) NegotiatedVersion() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.negotiatedVersion
+}
+
+// ClientExperimentalCapability returns the value the client advertised for
+// name under the experimental capability in its initialize request
+func (s *Server) ClientExperimentalCapability(name string) (interface{}, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	experimental, ok := s.clientCapabilities["experimental"].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	value, ok := experimental[name]
+	return value, ok
 }
 
 // handleMessage processes a single message
@@ -105,37 +661,330 @@ func (s *Server) handleMessage(ctx context.Context, msg *Message) {
 		return
 	}
 
-	// Before initialization, only handle initialize messages
-	if !s.initialized.Load() && msg.Method != "initialize" {
+	// Let handlers reach the server through their context
+	ctx = withLoggerName(withServer(ctx, s), msg.Method)
+
+	// A panicking handler fails its request rather than the whole server
+	defer func() {
+		if r := recover(); r != nil {
+			err, ok := r.(error)
+			if !ok {
+				err = fmt.Errorf("%v", r)
+			}
+			s.sendErrorData(ctx, msg.ID, -32603, "Internal error", s.debugErrorData(err, debug.Stack()))
+			s.reportError(ctx, fmt.Errorf("panic handling %s: %v", msg.Method, r))
+		}
+	}()
+
+	// Before initialization, only handle initialize and ping messages
+	if !s.initialized.Load() && msg.Method != "initialize" && msg.Method != "ping" {
 		s.sendError(ctx, msg.ID, -32002, "Server not initialized")
 		return
 	}
 
-	// Handle message based on method
+	// Notifications expect no reply, so never route them to request handlers
+	if msg.ID == nil {
+		s.handleNotification(ctx, msg)
+		return
+	}
+
+	// Requests can be cancelled by the client
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s.trackRequest(msg.ID, cancel)
+	defer s.untrackRequest(msg.ID)
+
+	// Bound the request by the client's timeout hint and the server's limit
+	if timeout := s.handlerTimeout(msg); timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+		defer cancelTimeout()
+	}
+
+	// Handle request based on method
+	if handler := s.LookupMethod(msg.Method); handler != nil {
+		handler(ctx, msg)
+		return
+	}
+
+	if fallback := s.fallback(); fallback != nil {
+		fallback(ctx, msg)
+		return
+	}
+	s.sendError(ctx, msg.ID, -32601, "Method not found")
+}
+
+// MethodHandler handles a request for one method. It answers the request
+// with Respond or RespondError before returning, as the request's context
+// is cancelled afterwards.
+type MethodHandler func(ctx context.Context, msg *Message)
+
+// builtinMethods returns the handlers for the methods the server implements
+func (s *Server) builtinMethods() map[string]MethodHandler {
+	return map[string]MethodHandler{
+		"initialize":       s.handleInitialize,
+		"ping":             s.handlePing,
+		"resources/list":   s.handleListResources,
+		"resources/read":   s.handleReadResource,
+		"tools/list":       s.handleListTools,
+		"tools/get":        s.handleGetTool,
+		"tools/call":       s.handleCallTool,
+		"prompts/list":     s.handleListPrompts,
+		"prompts/get":      s.handleGetPrompt,
+		"logging/setLevel": s.handleSetLevel,
+	}
+}
+
+// HandleMethod registers handler for requests with the given method,
+// replacing the built-in handler if there is one. To intercept a built-in
+// method, get its handler with LookupMethod first and call it from the
+// replacement. A nil handler removes the method.
+//
+// Requests other than initialize are still refused until the client has
+// initialized, so a replacement initialize handler should call the
+// built-in one.
+func (s *Server) HandleMethod(method string, handler MethodHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if handler == nil {
+		delete(s.methods, method)
+		return
+	}
+	s.methods[method] = handler
+}
+
+// LookupMethod returns the handler registered for method, or nil if there
+// is none
+func (s *Server) LookupMethod(method string) MethodHandler {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.methods[method]
+}
+
+// Respond sends a successful response to the request with the given ID
+func (s *Server) Respond(ctx context.Context, id json.RawMessage, result interface{}) {
+	s.sendResult(ctx, id, result)
+}
+
+// RespondError sends an error response to the request with the given ID
+func (s *Server) RespondError(ctx context.Context, id json.RawMessage, code int, message string) {
+	s.sendError(ctx, id, code, message)
+}
+
+// FallbackHandler handles a message whose method the server does not know.
+// Requests have a non-nil ID and should be answered with SendMessage before
+// the handler returns, as the request's context is cancelled afterwards.
+type FallbackHandler func(ctx context.Context, msg *Message)
+
+// SetFallbackHandler sets a handler for requests and notifications with
+// methods the server does not handle itself, such as to forward them to
+// another server. Without one, unknown requests get a -32601 error and
+// unknown notifications are ignored.
+func (s *Server) SetFallbackHandler(handler FallbackHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fallbackHandler = handler
+}
+
+// fallback returns the fallback handler, if any
+func (s *Server) fallback() FallbackHandler {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.fallbackHandler
+}
+
+// SendMessage sends a message to the client as is, through the OnSend hook.
+// Fallback handlers use it to answer requests.
+func (s *Server) SendMessage(ctx context.Context, msg *Message) error {
+	return s.send(ctx, msg)
+}
+
+// handlerTimeout returns how long a request may run: the smaller of the
+// server's request timeout and the client's _meta.timeoutMs hint, or zero
+// if neither is set
+func (s *Server) handlerTimeout(msg *Message) time.Duration {
+	timeout := s.requestTimeout
+
+	var params struct {
+		Meta struct {
+			TimeoutMs int64 `json:"timeoutMs"`
+		} `json:"_meta"`
+	}
+	if err := json.Unmarshal(msg.Params, &params); err == nil && params.Meta.TimeoutMs > 0 {
+		hint := time.Duration(params.Meta.TimeoutMs) * time.Millisecond
+		if timeout == 0 || hint < timeout {
+			timeout = hint
+		}
+	}
+
+	return timeout
+}
+
+// newRequestID allocates an ID for a server-initiated request
+func (s *Server) newRequestID() json.RawMessage {
+	if s.idGenerator != nil {
+		return s.idGenerator()
+	}
+
+	id := atomic.AddInt64(&s.nextID, 1)
+	return json.RawMessage(strconv.FormatInt(id, 10))
+}
+
+// request sends a request to the client and waits for its response. It
+// returns the response's error if the client reported one. Only pings may
+// be sent before the client has finished initializing.
+func (s *Server) request(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	if method != "ping" && !s.ready.Load() {
+		return nil, ErrNotInitialized
+	}
+
+	var paramsBytes json.RawMessage
+	if params != nil {
+		var err error
+		if paramsBytes, err = marshalJSON(params); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := s.getTransport(); err != nil {
+		return nil, err
+	}
+
+	s.transportMu.RLock()
+	connDone := s.connCtx.Done()
+	s.transportMu.RUnlock()
+
+	id := s.newRequestID()
+	response := make(chan *Message, 1)
+
+	s.pendingMu.Lock()
+	if s.pending == nil {
+		s.pending = make(map[string]chan *Message)
+	}
+	s.pending[string(id)] = response
+	s.pendingMu.Unlock()
+
+	defer func() {
+		s.pendingMu.Lock()
+		delete(s.pending, string(id))
+		s.pendingMu.Unlock()
+	}()
+
+	request := &Message{
+		ID:      id,
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  paramsBytes,
+	}
+
+	if err := s.send(ctx, request); err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-connDone:
+		return nil, ErrTransportClosed
+	case msg := <-response:
+		if msg.Error != nil {
+			return nil, msg.Error
+		}
+		return msg.Result, nil
+	}
+}
+
+// handleResponse delivers a response to the request waiting for it.
+// Responses to unknown or abandoned requests are dropped.
+func (s *Server) handleResponse(msg *Message) {
+	s.pendingMu.Lock()
+	response, ok := s.pending[string(msg.ID)]
+	delete(s.pending, string(msg.ID))
+	s.pendingMu.Unlock()
+
+	if ok {
+		response <- msg
+	}
+}
+
+// handleNotification processes a single notification. Unknown notifications
+// are ignored.
+func (s *Server) handleNotification(ctx context.Context, msg *Message) {
 	switch msg.Method {
-	case "initialize":
-		s.handleInitialize(ctx, msg)
-	case "initialized":
-		// No response needed for this notification
-	case "resources/list":
-		s.handleListResources(ctx, msg)
-	case "resources/read":
-		s.handleReadResource(ctx, msg)
-	case "tools/list":
-		s.handleListTools(ctx, msg)
-	case "tools/call":
-		s.handleCallTool(ctx, msg)
-	case "prompts/list":
-		s.handleListPrompts(ctx, msg)
-	case "prompts/get":
-		s.handleGetPrompt(ctx, msg)
+	case "notifications/initialized", "initialized":
+		// The client has finished initializing. The bare "initialized"
+		// method predates the spec and is still accepted.
+		s.ready.Store(true)
+	case "notifications/cancelled":
+		s.handleCancelled(msg)
 	default:
-		s.sendError(ctx, msg.ID, -32601, "Method not found")
+		if fallback := s.fallback(); fallback != nil {
+			fallback(ctx, msg)
+		}
 	}
 }
 
+// trackRequest records how to cancel an in-flight request
+func (s *Server) trackRequest(id json.RawMessage, cancel context.CancelFunc) {
+	s.inflightMu.Lock()
+	defer s.inflightMu.Unlock()
+
+	if s.requestCancels == nil {
+		s.requestCancels = make(map[string]context.CancelFunc)
+	}
+	s.requestCancels[string(id)] = cancel
+}
+
+// untrackRequest forgets a finished request
+func (s *Server) untrackRequest(id json.RawMessage) {
+	s.inflightMu.Lock()
+	defer s.inflightMu.Unlock()
+
+	delete(s.requestCancels, string(id))
+}
+
+// handleCancelled processes a notifications/cancelled notification by
+// cancelling the context of the referenced request, if it is still running
+func (s *Server) handleCancelled(msg *Message) {
+	var params struct {
+		RequestID json.RawMessage `json:"requestId"`
+		Reason    string          `json:"reason,omitempty"`
+	}
+
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return
+	}
+
+	s.inflightMu.Lock()
+	cancel, ok := s.requestCancels[string(params.RequestID)]
+	s.inflightMu.Unlock()
+
+	if ok {
+		cancel()
+	}
+}
+
+// requireParams answers a request that omitted its params with -32602 and
+// reports whether the params are present
+func (s *Server) requireParams(ctx context.Context, msg *Message) bool {
+	if len(msg.Params) == 0 || string(msg.Params) == "null" {
+		s.sendError(ctx, msg.ID, -32602, fmt.Sprintf("Missing params for %s", msg.Method))
+		return false
+	}
+	return true
+}
+
 // handleInitialize processes an initialize request
 func (s *Server) handleInitialize(ctx context.Context, msg *Message) {
+	if !s.requireParams(ctx, msg) {
+		return
+	}
+
 	// Parse request
 	var params struct {
 		ProtocolVersion string `json:"protocolVersion"`
@@ -151,19 +1000,43 @@ func (s *Server) handleInitialize(ctx context.Context, msg *Message) {
 		return
 	}
 
+	// Answer in the client's version when the server speaks it, unless the
+	// version is pinned
+	version := s.protocolVersion
+	if version == "" {
+		version = ProtocolVersion
+		if supportedProtocolVersions[params.ProtocolVersion] {
+			version = params.ProtocolVersion
+		}
+	}
+
 	// Prepare response
 	result := struct {
 		ProtocolVersion string                 `json:"protocolVersion"`
 		ServerInfo      ServerInfo             `json:"serverInfo"`
 		Capabilities    map[string]interface{} `json:"capabilities"`
 	}{
-		ProtocolVersion: ProtocolVersion,
+		ProtocolVersion: version,
 		ServerInfo:      s.info,
 		Capabilities:    s.capabilities,
 	}
 
-	// Set server as initialized
-	s.initialized.Store(true)
+	// Set server as initialized; a connection is only initialized once
+	if !s.initialized.CompareAndSwap(false, true) {
+		s.sendError(ctx, msg.ID, -32600, "Server already initialized")
+		return
+	}
+
+	// Remember what the client supports and the version in use
+	s.mu.Lock()
+	s.clientCapabilities = params.Capabilities
+	s.negotiatedVersion = result.ProtocolVersion
+	hook := s.initializeHook
+	s.mu.Unlock()
+
+	if hook != nil {
+		hook(ctx, msg.Params)
+	}
 
 	// Send response
 	s.sendResult(ctx, msg.ID, result)
@@ -175,63 +1048,176 @@ func (s *Server) sendResult(ctx context.Context, id json.RawMessage, result inte
 		return // Skip responses to notifications
 	}
 
-	resultBytes, err := json.Marshal(result)
+	resultBytes, err := marshalJSON(result)
 	if err != nil {
 		s.sendError(ctx, id, -32603, "Internal error")
 		return
 	}
 
+	s.sendRawResult(ctx, id, resultBytes)
+}
+
+// Send a result response that is already serialized
+func (s *Server) sendRawResult(ctx context.Context, id json.RawMessage, resultBytes json.RawMessage) {
+	if id == nil {
+		return // Skip responses to notifications
+	}
+
+	// Respond even if the request's context has expired
+	ctx = context.WithoutCancel(ctx)
+
 	response := &Message{
 		ID:      id,
 		JSONRPC: "2.0",
 		Result:  resultBytes,
 	}
 
-	if err := s.transport.Send(ctx, response); err != nil {
-		// TODO: Log error
+	if err := s.send(ctx, response); err != nil {
+		s.reportError(ctx, fmt.Errorf("sending response to request %s: %w", id, err))
 	}
 }
 
 // Send an error response
 func (s *Server) sendError(ctx context.Context, id json.RawMessage, code int, message string) {
+	s.sendErrorData(ctx, id, code, message, nil)
+}
+
+// Send an error response with additional data
+func (s *Server) sendErrorData(ctx context.Context, id json.RawMessage, code int, message string, data interface{}) {
 	if id == nil {
 		return // Skip responses to notifications
 	}
 
+	// Respond even if the request's context has expired
+	ctx = context.WithoutCancel(ctx)
+
+	var dataBytes json.RawMessage
+	if data != nil {
+		dataBytes, _ = marshalJSON(data)
+	}
+
 	response := &Message{
 		ID:      id,
 		JSONRPC: "2.0",
 		Error: &ErrorMessage{
 			Code:    code,
 			Message: message,
+			Data:    dataBytes,
 		},
 	}
 
-	if err := s.transport.Send(ctx, response); err != nil {
-		// TODO: Log error
+	if err := s.send(ctx, response); err != nil {
+		s.reportError(ctx, fmt.Errorf("sending response to request %s: %w", id, err))
+	}
+}
+
+// sendHandlerError sends an error response for a handler that returned err,
+// with debugging details when WithDebugErrors is set
+func (s *Server) sendHandlerError(ctx context.Context, id json.RawMessage, code int, message string, err error) {
+	s.sendErrorData(ctx, id, code, message, s.debugErrorData(err, nil))
+}
+
+// debugErrorData returns the data for an error response describing err and,
+// for a panic, the stack where it happened. It returns nil unless
+// WithDebugErrors is set.
+func (s *Server) debugErrorData(err error, stack []byte) interface{} {
+	if !s.debugErrors {
+		return nil
+	}
+
+	var chain []string
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		chain = append(chain, e.Error())
+	}
+
+	return struct {
+		ErrorChain []string `json:"errorChain"`
+		Stack      string   `json:"stack,omitempty"`
+	}{
+		ErrorChain: chain,
+		Stack:      string(stack),
+	}
+}
+
+// describeMessage names an outbound request or notification for error
+// reports by its method and ID
+func describeMessage(msg *Message) string {
+	if msg.ID != nil {
+		return fmt.Sprintf("request %s %s", msg.Method, msg.ID)
+	}
+	return fmt.Sprintf("notification %s", msg.Method)
+}
+
+// reportError surfaces an internal failure that has no request to answer,
+// such as a panic in a notification handler or a failed send. With
+// WithErrorLogging and the logging capability enabled it is sent to the
+// client as an error-level log message; otherwise it is dropped.
+func (s *Server) reportError(ctx context.Context, err error) {
+	if !s.errorLogging {
+		return
+	}
+	if _, ok := s.capabilities["logging"]; !ok {
+		return
+	}
+
+	s.SendLogMessage(context.WithoutCancel(ctx), LogLevelError, err.Error(), "mcp")
+}
+
+// handlePing answers a ping request with an empty result
+func (s *Server) handlePing(ctx context.Context, msg *Message) {
+	s.sendResult(ctx, msg.ID, struct{}{})
+}
+
+// Ping sends a ping request to the client and returns the round-trip time
+// once the client answers
+func (s *Server) Ping(ctx context.Context) (time.Duration, error) {
+	start := time.Now()
+	if _, err := s.request(ctx, "ping", nil); err != nil {
+		return 0, err
 	}
+
+	return time.Since(start), nil
 }
 
-// SendLogMessage sends a logging message notification to the client
+// handleSetLevel handles a logging/setLevel request
+func (s *Server) handleSetLevel(ctx context.Context, msg *Message) {
+	if !s.requireParams(ctx, msg) {
+		return
+	}
+
+	var params struct {
+		Level string `json:"level"`
+	}
+
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		s.sendError(ctx, msg.ID, -32700, "Parse error")
+		return
+	}
+
+	severity, ok := logLevelSeverity[params.Level]
+	if !ok {
+		s.sendError(ctx, msg.ID, -32602, "Invalid log level")
+		return
+	}
+
+	s.minLogSeverity.Store(int32(severity))
+	s.sendResult(ctx, msg.ID, struct{}{})
+}
+
+// SendLogMessage sends a logging message notification to the client.
+// Messages below the minimum level requested by the client are dropped.
 func (s *Server) SendLogMessage(ctx context.Context, level string, data interface{}, logger string) error {
+	if logLevelSeverity[level] < int(s.minLogSeverity.Load()) {
+		return nil
+	}
+
 	params := LoggingMessageParams{
 		Level:  level,
 		Data:   data,
 		Logger: logger,
 	}
 
-	paramsBytes, err := json.Marshal(params)
-	if err != nil {
-		return err
-	}
-
-	notification := &Message{
-		JSONRPC: "2.0",
-		Method:  "notifications/message",
-		Params:  paramsBytes,
-	}
-
-	return s.transport.Send(ctx, notification)
+	return s.notify(ctx, "notifications/message", params)
 }
 
 // Helper methods for common log levels
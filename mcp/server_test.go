@@ -0,0 +1,1246 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testTimeout bounds how long a test waits for any single message
+const testTimeout = 2 * time.Second
+
+// testClient drives a Server from the client end of an in-memory transport
+type testClient struct {
+	t         *testing.T
+	server    *Server
+	transport *InMemoryTransport
+	nextID    int
+}
+
+// connect attaches s to an in-memory transport and returns the client end
+// without initializing
+func connect(t *testing.T, s *Server) *testClient {
+	t.Helper()
+
+	a, b := NewInMemoryTransportPair()
+	if err := s.Connect(context.Background(), a); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return &testClient{t: t, server: s, transport: b}
+}
+
+// newTestClient connects to s and completes the initialize handshake
+func newTestClient(t *testing.T, s *Server) *testClient {
+	t.Helper()
+
+	c := connect(t, s)
+	c.initialize()
+	return c
+}
+
+// initialize performs the initialize handshake with no client capabilities
+func (c *testClient) initialize() *Message {
+	c.t.Helper()
+
+	resp := c.initializeWith(map[string]interface{}{})
+	c.notify("notifications/initialized", nil)
+	return resp
+}
+
+// initializeWith sends an initialize request declaring capabilities,
+// without the notifications/initialized that completes the handshake
+func (c *testClient) initializeWith(capabilities map[string]interface{}) *Message {
+	c.t.Helper()
+
+	resp := c.call("initialize", initializeParams(ProtocolVersion, capabilities))
+	if resp.Error != nil {
+		c.t.Fatalf("initialize: %s", resp.Error.Message)
+	}
+	return resp
+}
+
+func initializeParams(version string, capabilities map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"protocolVersion": version,
+		"capabilities":    capabilities,
+		"clientInfo":      map[string]interface{}{"name": "test", "version": "1.0"},
+	}
+}
+
+// waitFor polls cond until it holds, failing the test after testTimeout
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(testTimeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// send writes msg to the server
+func (c *testClient) send(msg *Message) {
+	c.t.Helper()
+
+	if err := c.transport.Send(context.Background(), msg); err != nil {
+		c.t.Fatalf("send %s: %v", msg.Method, err)
+	}
+}
+
+// notify sends a notification. A nil params is omitted.
+func (c *testClient) notify(method string, params interface{}) {
+	c.t.Helper()
+
+	c.send(&Message{JSONRPC: "2.0", Method: method, Params: mustMarshal(c.t, params)})
+}
+
+// request sends a request without waiting for the response and returns its ID
+func (c *testClient) request(method string, params interface{}) json.RawMessage {
+	c.t.Helper()
+
+	c.nextID++
+	id := json.RawMessage(fmt.Sprint(c.nextID))
+	c.send(&Message{JSONRPC: "2.0", ID: id, Method: method, Params: mustMarshal(c.t, params)})
+	return id
+}
+
+// call sends a request and returns its response, skipping any
+// notifications or server requests that arrive first
+func (c *testClient) call(method string, params interface{}) *Message {
+	c.t.Helper()
+
+	return c.response(c.request(method, params))
+}
+
+// response waits for the response to the request with the given ID
+func (c *testClient) response(id json.RawMessage) *Message {
+	c.t.Helper()
+
+	for {
+		msg := c.receive()
+		if msg.Method == "" && string(msg.ID) == string(id) {
+			return msg
+		}
+	}
+}
+
+// receive returns the next message from the server
+func (c *testClient) receive() *Message {
+	c.t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	msg, err := c.transport.Receive(ctx)
+	if err != nil {
+		c.t.Fatalf("receive: %v", err)
+	}
+	return msg
+}
+
+// result calls method and decodes its result into v, failing on an error
+// response
+func (c *testClient) result(method string, params interface{}, v interface{}) {
+	c.t.Helper()
+
+	resp := c.call(method, params)
+	if resp.Error != nil {
+		c.t.Fatalf("%s: error %d: %s", method, resp.Error.Code, resp.Error.Message)
+	}
+	if err := json.Unmarshal(resp.Result, v); err != nil {
+		c.t.Fatalf("%s: decoding result: %v", method, err)
+	}
+}
+
+// callError calls method and returns its error, failing unless the code is
+// want
+func (c *testClient) callError(method string, params interface{}, want int) *ErrorMessage {
+	c.t.Helper()
+
+	resp := c.call(method, params)
+	if resp.Error == nil {
+		c.t.Fatalf("%s: got result %s, want error %d", method, resp.Result, want)
+	}
+	if resp.Error.Code != want {
+		c.t.Fatalf("%s: got error %d %q, want %d", method, resp.Error.Code, resp.Error.Message, want)
+	}
+	return resp.Error
+}
+
+// toolResult is a decoded tools/call result
+type toolResult struct {
+	Content           []ToolContent   `json:"content"`
+	IsError           bool            `json:"isError"`
+	StructuredContent json.RawMessage `json:"structuredContent"`
+	Meta              json.RawMessage `json:"_meta"`
+}
+
+// text returns the text of the result's first content item
+func (r toolResult) text() string {
+	if len(r.Content) == 0 {
+		return ""
+	}
+	return r.Content[0].Text
+}
+
+// callTool calls the named tool and decodes its result
+func (c *testClient) callTool(name string, args interface{}) toolResult {
+	c.t.Helper()
+
+	var result toolResult
+	c.result("tools/call", map[string]interface{}{"name": name, "arguments": args}, &result)
+	return result
+}
+
+// textTool returns a handler that answers with the text f computes
+func textTool(f func(ctx context.Context, args map[string]interface{}) string) ToolHandler {
+	return func(ctx context.Context, args map[string]interface{}) ([]ToolContent, error) {
+		return []ToolContent{{Type: "text", Text: f(ctx, args)}}, nil
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) json.RawMessage {
+	t.Helper()
+
+	if v == nil {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSendLimitBelowOne(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		s := NewServer("test", "1.0", WithSendLimit(n))
+		c := connect(t, s)
+		c.initialize()
+
+		if err := s.SendLogMessage(context.Background(), "info", "hello", ""); err != nil {
+			t.Errorf("WithSendLimit(%d): notification failed: %v", n, err)
+		}
+	}
+}
+
+func TestSendLimitStalledReader(t *testing.T) {
+	const limit = 4
+
+	s := NewServer("test", "1.0", WithSendLimit(limit))
+	c := newTestClient(t, s)
+	ctx := context.Background()
+
+	// Fill the transport's buffer while the client is not reading
+	var buffered int
+	for ; buffered < 100; buffered++ {
+		sendCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		err := s.SendLogMessage(sendCtx, "info", buffered, "")
+		cancel()
+		if err != nil {
+			break
+		}
+	}
+
+	// The send that found the buffer full timed out and released its slot;
+	// hold every slot with sends that block on the stalled reader
+	errs := make(chan error, limit)
+	for i := 0; i < limit; i++ {
+		go func() { errs <- s.SendLogMessage(ctx, "info", "blocked", "") }()
+	}
+	waitFor(t, "blocked sends", func() bool { return s.PendingSends() == limit })
+
+	if err := s.SendLogMessage(ctx, "info", "overflow", ""); err != ErrSendQueueFull {
+		t.Fatalf("send with full queue: got %v, want ErrSendQueueFull", err)
+	}
+
+	// Once the client reads, the blocked sends complete
+	for i := 0; i < buffered+limit; i++ {
+		c.receive()
+	}
+	for i := 0; i < limit; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("blocked send: %v", err)
+		}
+	}
+	if n := s.PendingSends(); n != 0 {
+		t.Errorf("PendingSends after drain = %d, want 0", n)
+	}
+}
+
+func TestInitializedNotification(t *testing.T) {
+	s := NewServer("test", "1.0")
+	c := connect(t, s)
+
+	c.callError("tools/list", nil, -32002)
+
+	c.initializeWith(map[string]interface{}{"elicitation": map[string]interface{}{}})
+	if _, err := s.Elicit(context.Background(), "name?", nil); err != ErrNotInitialized {
+		t.Fatalf("Elicit before notifications/initialized: got %v, want ErrNotInitialized", err)
+	}
+
+	c.notify("notifications/initialized", nil)
+	waitFor(t, "initialized notification", s.ready.Load)
+
+	var result struct{ Tools []Tool }
+	c.result("tools/list", nil, &result)
+
+	c.callError("initialize", initializeParams(ProtocolVersion, nil), -32600)
+}
+
+func TestReconnectResetsInitialization(t *testing.T) {
+	s := NewServer("test", "1.0", WithInitializeTimeout(100*time.Millisecond))
+
+	first := connect(t, s)
+	first.initializeWith(map[string]interface{}{"experimental": map[string]interface{}{"x": true}})
+	if _, ok := s.ClientExperimentalCapability("x"); !ok {
+		t.Fatal("first client's experimental capability not recorded")
+	}
+	s.Close()
+
+	second := connect(t, s)
+	if _, ok := s.ClientExperimentalCapability("x"); ok {
+		t.Error("first client's capabilities survived reconnect")
+	}
+	if v := s.NegotiatedVersion(); v != "" {
+		t.Errorf("NegotiatedVersion before initialize = %q, want empty", v)
+	}
+	second.initialize()
+
+	// The initialize timeout applies to every connection
+	s.Close()
+	connect(t, s)
+	select {
+	case <-s.Done():
+	case <-time.After(testTimeout):
+		t.Fatal("initialize timeout did not close the second connection")
+	}
+}
+
+// failingReader returns err from every read
+type failingReader struct{ err error }
+
+func (r failingReader) Read([]byte) (int, error) { return 0, r.err }
+
+func TestDoneAfterEOF(t *testing.T) {
+	s := NewServer("test", "1.0")
+	c := connect(t, s)
+	c.transport.Close()
+
+	select {
+	case <-s.Done():
+	case <-time.After(testTimeout):
+		t.Fatal("Done not closed after EOF")
+	}
+	if err := s.Err(); err != io.EOF {
+		t.Errorf("Err = %v, want io.EOF", err)
+	}
+}
+
+func TestDoneAfterReadError(t *testing.T) {
+	readErr := errors.New("disk on fire")
+
+	s := NewServer("test", "1.0")
+	transport := NewStdioTransportWithIO(failingReader{readErr}, io.Discard)
+	if err := s.Connect(context.Background(), transport); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	select {
+	case <-s.Done():
+	case <-time.After(testTimeout):
+		t.Fatal("Done not closed after a read error")
+	}
+	if err := s.Err(); err != readErr {
+		t.Errorf("Err = %v, want %v", err, readErr)
+	}
+}
+
+func TestMalformedMessageSkipped(t *testing.T) {
+	in := strings.NewReader("{not json}\n" + `{"jsonrpc":"1.0","id":1,"method":"ping"}` + "\n" + `{"jsonrpc":"2.0","id":2,"method":"ping"}` + "\n")
+	var out bytes.Buffer
+
+	s := NewServer("test", "1.0")
+	transport := NewStdioTransportWithIO(in, &out)
+	if err := s.ConnectManual(context.Background(), transport); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Serve(context.Background()); err != io.EOF {
+		t.Fatalf("Serve = %v, want io.EOF", err)
+	}
+
+	var resp Message
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding output %q: %v", out.String(), err)
+	}
+	if string(resp.ID) != "2" || resp.Error != nil {
+		t.Errorf("got %s, want the ping response to id 2", out.String())
+	}
+}
+
+// errTest is a handler failure
+var errTest = errors.New("test failure")
+
+func TestNotConnected(t *testing.T) {
+	s := NewServer("test", "1.0")
+	ctx := context.Background()
+
+	if err := s.NotifyToolsChanged(ctx); err != ErrNotConnected {
+		t.Errorf("NotifyToolsChanged = %v, want ErrNotConnected", err)
+	}
+	if err := s.SendLogMessage(ctx, "info", "hello", ""); err != ErrNotConnected {
+		t.Errorf("SendLogMessage = %v, want ErrNotConnected", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("Close = %v", err)
+	}
+}
+
+func TestCloseCancelsHandlers(t *testing.T) {
+	started := make(chan struct{})
+	cancelled := make(chan error, 1)
+
+	s := NewServer("test", "1.0")
+	s.AddTool("slow", "", nil, func(ctx context.Context, _ map[string]interface{}) ([]ToolContent, error) {
+		close(started)
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+		return nil, ctx.Err()
+	})
+	c := newTestClient(t, s)
+
+	c.request("tools/call", map[string]interface{}{"name": "slow"})
+	<-started
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-cancelled:
+		if err != context.Canceled {
+			t.Errorf("handler context error = %v, want context.Canceled", err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("handler context not cancelled by Close")
+	}
+}
+
+func TestInitializeTimeout(t *testing.T) {
+	disconnected := make(chan struct{})
+
+	s := NewServer("test", "1.0", WithInitializeTimeout(20*time.Millisecond))
+	s.OnDisconnect(func() { close(disconnected) })
+	connect(t, s)
+
+	select {
+	case <-disconnected:
+	case <-time.After(testTimeout):
+		t.Fatal("connection without initialize was not closed")
+	}
+	<-s.Done()
+}
+
+// serverCapabilities initializes a client of s and returns the capabilities
+// the server advertised
+func serverCapabilities(t *testing.T, s *Server) map[string]interface{} {
+	t.Helper()
+
+	var init struct {
+		Capabilities map[string]interface{}
+	}
+	resp := connect(t, s).initialize()
+	if err := json.Unmarshal(resp.Result, &init); err != nil {
+		t.Fatal(err)
+	}
+	return init.Capabilities
+}
+
+func TestWithoutCapabilities(t *testing.T) {
+	all := serverCapabilities(t, NewServer("test", "1.0"))
+	for _, name := range []string{"tools", "resources", "prompts", "logging"} {
+		if _, ok := all[name]; !ok {
+			t.Errorf("default server does not advertise %s", name)
+		}
+	}
+
+	s := NewServer("test", "1.0", WithoutLogging(), WithoutPrompts())
+	caps := serverCapabilities(t, s)
+	for _, name := range []string{"logging", "prompts"} {
+		if _, ok := caps[name]; ok {
+			t.Errorf("%s advertised after being disabled", name)
+		}
+	}
+	for _, name := range []string{"tools", "resources"} {
+		if _, ok := caps[name]; !ok {
+			t.Errorf("%s no longer advertised", name)
+		}
+	}
+}
+
+func TestExperimentalCapability(t *testing.T) {
+	s := NewServer("test", "1.0", WithExperimentalCapability("streaming", map[string]interface{}{"chunkSize": 1024}))
+	c := connect(t, s)
+
+	var init struct {
+		Capabilities struct {
+			Experimental map[string]struct{ ChunkSize int }
+		}
+	}
+	resp := c.initializeWith(map[string]interface{}{
+		"experimental": map[string]interface{}{"batching": true},
+	})
+	if err := json.Unmarshal(resp.Result, &init); err != nil {
+		t.Fatal(err)
+	}
+	if got := init.Capabilities.Experimental["streaming"].ChunkSize; got != 1024 {
+		t.Errorf("advertised streaming chunkSize = %d, want 1024", got)
+	}
+
+	if v, ok := s.ClientExperimentalCapability("batching"); !ok || v != true {
+		t.Errorf("client batching capability = %v, %v", v, ok)
+	}
+	if _, ok := s.ClientExperimentalCapability("missing"); ok {
+		t.Error("found a capability the client did not advertise")
+	}
+}
+
+func TestSerialMethod(t *testing.T) {
+	const calls = 5
+	release := make(chan struct{})
+
+	s := NewServer("test", "1.0", WithSerialMethod("tools/call"))
+	s.AddTool("step", "", nil, textTool(func(_ context.Context, args map[string]interface{}) string {
+		n, _ := args["n"].(json.Number).Int64()
+		if n == 0 {
+			<-release
+		}
+		// Earlier calls take longer, so concurrent handling would reorder them
+		time.Sleep(time.Duration(calls-n) * time.Millisecond)
+		return fmt.Sprint(n)
+	}))
+	c := newTestClient(t, s)
+
+	for n := 0; n < calls; n++ {
+		c.request("tools/call", map[string]interface{}{"name": "step", "arguments": map[string]interface{}{"n": n}})
+	}
+
+	// Other methods are still handled while the serial queue is blocked
+	var pong struct{}
+	c.result("ping", nil, &pong)
+	close(release)
+
+	for n := 0; n < calls; n++ {
+		msg := c.receive()
+		var result toolResult
+		if err := json.Unmarshal(msg.Result, &result); err != nil {
+			t.Fatal(err)
+		}
+		if result.text() != fmt.Sprint(n) {
+			t.Fatalf("response %d was for call %s", n, result.text())
+		}
+	}
+}
+
+func TestCancelledNotification(t *testing.T) {
+	started := make(chan struct{})
+	cancelled := make(chan error, 1)
+
+	s := NewServer("test", "1.0")
+	s.AddTool("slow", "", nil, func(ctx context.Context, _ map[string]interface{}) ([]ToolContent, error) {
+		close(started)
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+		return nil, ctx.Err()
+	})
+	c := newTestClient(t, s)
+
+	id := c.request("tools/call", map[string]interface{}{"name": "slow"})
+	<-started
+	c.notify("notifications/cancelled", map[string]interface{}{"requestId": id, "reason": "user gave up"})
+
+	select {
+	case err := <-cancelled:
+		if err != context.Canceled {
+			t.Errorf("handler context error = %v, want context.Canceled", err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("handler not cancelled")
+	}
+
+	// The cancelled request gets no response, so the next message is the
+	// answer to a later ping
+	pingID := c.request("ping", nil)
+	if msg := c.receive(); string(msg.ID) != string(pingID) {
+		t.Errorf("got a message for id %s after cancellation, want the ping response", msg.ID)
+	}
+
+	// Cancelling an unknown or finished request is ignored
+	c.notify("notifications/cancelled", map[string]interface{}{"requestId": id})
+	c.notify("notifications/cancelled", map[string]interface{}{"requestId": 999})
+	var pong struct{}
+	c.result("ping", nil, &pong)
+}
+
+func TestRequestMethodAsNotification(t *testing.T) {
+	var called atomic.Bool
+
+	s := NewServer("test", "1.0")
+	s.AddTool("echo", "", nil, textTool(func(context.Context, map[string]interface{}) string {
+		called.Store(true)
+		return "echo"
+	}))
+	c := newTestClient(t, s)
+
+	c.notify("tools/call", map[string]interface{}{"name": "echo"})
+	c.notify("tools/list", nil)
+	c.notify("no/such/method", nil)
+
+	// Nothing answers the notifications, so the next message is the ping's
+	pingID := c.request("ping", nil)
+	if msg := c.receive(); string(msg.ID) != string(pingID) {
+		t.Errorf("got %s %s before the ping response", msg.ID, msg.Error)
+	}
+	if called.Load() {
+		t.Error("a tools/call notification ran the tool")
+	}
+}
+
+// answerPings responds to n server pings and returns their IDs
+func (c *testClient) answerPings(n int) []string {
+	c.t.Helper()
+
+	var ids []string
+	for len(ids) < n {
+		msg := c.receive()
+		if msg.Method != "ping" {
+			continue
+		}
+		ids = append(ids, string(msg.ID))
+		c.send(&Message{JSONRPC: "2.0", ID: msg.ID, Result: json.RawMessage(`{}`)})
+	}
+	return ids
+}
+
+func TestIDGenerator(t *testing.T) {
+	var n atomic.Int64
+	generate := func() json.RawMessage {
+		return json.RawMessage(fmt.Sprintf(`"req-%d"`, n.Add(1)))
+	}
+
+	for _, tt := range []struct {
+		name     string
+		generate func() json.RawMessage
+		check    func(id string) bool
+	}{
+		{"default", nil, func(id string) bool { _, err := strconv.Atoi(id); return err == nil }},
+		{"custom", generate, func(id string) bool { return strings.HasPrefix(id, `"req-`) }},
+		{"uuid", UUIDGenerator, func(id string) bool { return len(id) == 38 && id[0] == '"' }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []ServerOption
+			if tt.generate != nil {
+				opts = append(opts, WithIDGenerator(tt.generate))
+			}
+			s := NewServer("test", "1.0", opts...)
+			c := newTestClient(t, s)
+			waitFor(t, "initialized notification", s.ready.Load)
+
+			const pings = 3
+			errs := make(chan error, pings)
+			for i := 0; i < pings; i++ {
+				go func() {
+					_, err := s.Ping(context.Background())
+					errs <- err
+				}()
+			}
+
+			seen := make(map[string]bool)
+			for _, id := range c.answerPings(pings) {
+				if !tt.check(id) {
+					t.Errorf("unexpected ID %s", id)
+				}
+				if seen[id] {
+					t.Errorf("ID %s used twice", id)
+				}
+				seen[id] = true
+			}
+			for i := 0; i < pings; i++ {
+				if err := <-errs; err != nil {
+					t.Errorf("Ping: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestSendHook(t *testing.T) {
+	errDropped := errors.New("dropped")
+
+	s := NewServer("test", "1.0")
+	s.AddTool("secret", "", nil, textTool(func(context.Context, map[string]interface{}) string {
+		return "password: hunter2"
+	}))
+	s.OnSend(func(msg *Message) (*Message, error) {
+		if msg.Method == "notifications/message" {
+			return nil, errDropped
+		}
+		if msg.Result != nil {
+			redacted := *msg
+			redacted.Result = bytes.ReplaceAll(msg.Result, []byte("hunter2"), []byte("***"))
+			return &redacted, nil
+		}
+		return msg, nil
+	})
+	c := newTestClient(t, s)
+
+	if got := c.callTool("secret", nil).text(); got != "password: ***" {
+		t.Errorf("result = %q, want it redacted", got)
+	}
+
+	if err := s.SendLogMessage(context.Background(), LogLevelInfo, "hello", ""); err != errDropped {
+		t.Errorf("SendLogMessage = %v, want the hook's error", err)
+	}
+	pingID := c.request("ping", nil)
+	if msg := c.receive(); string(msg.ID) != string(pingID) {
+		t.Errorf("got %s %s, want the dropped log message skipped", msg.Method, msg.ID)
+	}
+}
+
+func TestSendHookErrorLogged(t *testing.T) {
+	errRejected := errors.New("rejected by policy")
+
+	s := NewServer("test", "1.0", WithErrorLogging())
+	s.AddTool("secret", "", nil, textTool(func(context.Context, map[string]interface{}) string {
+		return "password: hunter2"
+	}))
+	s.OnSend(func(msg *Message) (*Message, error) {
+		if bytes.Contains(msg.Result, []byte("hunter2")) || msg.Method == "notifications/tools/list_changed" {
+			return nil, errRejected
+		}
+		return msg, nil
+	})
+	c := newTestClient(t, s)
+
+	// expectLog reads the error log reporting a dropped message
+	expectLog := func(want string) {
+		t.Helper()
+
+		msg := c.receive()
+		var params LoggingMessageParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := params.Data.(string)
+		if msg.Method != "notifications/message" || params.Level != LogLevelError || !strings.Contains(data, want) || !strings.Contains(data, errRejected.Error()) {
+			t.Errorf("got %s %+v, want an error log about %s", msg.Method, params, want)
+		}
+	}
+
+	id := c.request("tools/call", map[string]interface{}{"name": "secret"})
+	expectLog("response to request " + string(id))
+
+	if err := s.NotifyToolsChanged(context.Background()); err != errRejected {
+		t.Errorf("NotifyToolsChanged = %v, want the hook's error", err)
+	}
+	expectLog("notification notifications/tools/list_changed")
+}
+
+func TestEverySendGoesThroughHook(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		sent []string
+	)
+
+	s := NewServer("test", "1.0")
+	s.OnSend(func(msg *Message) (*Message, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case msg.Method != "":
+			sent = append(sent, msg.Method)
+		case msg.Error != nil:
+			sent = append(sent, "error")
+		default:
+			sent = append(sent, "result")
+		}
+		return msg, nil
+	})
+	c := newTestClient(t, s)
+	waitFor(t, "initialized notification", s.ready.Load)
+	ctx := context.Background()
+
+	c.callError("no/such/method", nil, -32601)
+	for _, notify := range []func(context.Context) error{
+		s.NotifyToolsChanged,
+		s.NotifyResourcesChanged,
+		s.NotifyPromptsChanged,
+		func(ctx context.Context) error { return s.NotifyResourceUpdated(ctx, "file:///x") },
+		func(ctx context.Context) error { return s.LogInfo(ctx, "hello", "") },
+	} {
+		if err := notify(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	go s.Ping(ctx)
+	c.answerPings(1)
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{
+		"result", "error",
+		"notifications/tools/list_changed",
+		"notifications/resources/list_changed",
+		"notifications/prompts/list_changed",
+		"notifications/resources/updated",
+		"notifications/message",
+		"ping",
+	}
+	if !slices.Equal(sent, want) {
+		t.Errorf("hook saw %v, want %v", sent, want)
+	}
+}
+
+func TestTimeoutHint(t *testing.T) {
+	deadlineTool := textTool(func(ctx context.Context, _ map[string]interface{}) string {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			return "none"
+		}
+		return time.Until(deadline).Round(time.Second).String()
+	})
+	slowTool := func(ctx context.Context, _ map[string]interface{}) ([]ToolContent, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(testTimeout):
+			return []ToolContent{{Type: "text", Text: "finished"}}, nil
+		}
+	}
+	call := func(name string, timeoutMs int) map[string]interface{} {
+		params := map[string]interface{}{"name": name}
+		if timeoutMs > 0 {
+			params["_meta"] = map[string]interface{}{"timeoutMs": timeoutMs}
+		}
+		return params
+	}
+
+	s := NewServer("test", "1.0", WithRequestTimeout(time.Minute))
+	s.AddTool("deadline", "", nil, deadlineTool)
+	s.AddTool("slow", "", nil, slowTool)
+	c := newTestClient(t, s)
+
+	// The shorter of the hint and the server's limit applies
+	for _, tt := range []struct {
+		timeoutMs int
+		want      string
+	}{
+		{0, "1m0s"},
+		{5000, "5s"},
+		{120000, "1m0s"},
+	} {
+		var result toolResult
+		c.result("tools/call", call("deadline", tt.timeoutMs), &result)
+		if result.text() != tt.want {
+			t.Errorf("timeoutMs %d: deadline in %s, want %s", tt.timeoutMs, result.text(), tt.want)
+		}
+	}
+
+	start := time.Now()
+	c.callError("tools/call", call("slow", 20), -32800)
+	if elapsed := time.Since(start); elapsed > testTimeout/2 {
+		t.Errorf("slow handler ran for %s despite a 20ms hint", elapsed)
+	}
+
+	s = NewServer("test", "1.0")
+	s.AddTool("deadline", "", nil, deadlineTool)
+	if got := newTestClient(t, s).callTool("deadline", nil).text(); got != "none" {
+		t.Errorf("deadline without hint or limit = %s", got)
+	}
+}
+
+func TestFallbackHandler(t *testing.T) {
+	notified := make(chan string, 1)
+
+	s := NewServer("test", "1.0")
+	s.SetFallbackHandler(func(ctx context.Context, msg *Message) {
+		if msg.ID == nil {
+			notified <- msg.Method
+			return
+		}
+		s.SendMessage(ctx, &Message{JSONRPC: "2.0", ID: msg.ID, Result: json.RawMessage(`{"proxied":"` + msg.Method + `"}`)})
+	})
+	c := newTestClient(t, s)
+
+	var result struct{ Proxied string }
+	c.result("custom/echo", map[string]interface{}{"x": 1}, &result)
+	if result.Proxied != "custom/echo" {
+		t.Errorf("fallback answered %+v", result)
+	}
+
+	// Built-in methods never reach the fallback
+	var tools struct{ Tools []Tool }
+	c.result("tools/list", nil, &tools)
+
+	c.notify("custom/event", nil)
+	select {
+	case method := <-notified:
+		if method != "custom/event" {
+			t.Errorf("fallback notified of %s", method)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("fallback not called for an unknown notification")
+	}
+
+	s.SetFallbackHandler(nil)
+	c.callError("custom/echo", nil, -32601)
+}
+
+func TestErrorLogging(t *testing.T) {
+	panicky := func(ctx context.Context, msg *Message) {
+		panic("handler exploded")
+	}
+
+	s := NewServer("test", "1.0", WithErrorLogging())
+	s.SetFallbackHandler(panicky)
+	c := newTestClient(t, s)
+
+	c.notify("custom/event", nil)
+	msg := c.receive()
+	var params LoggingMessageParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Method != "notifications/message" || params.Level != LogLevelError || params.Logger != "mcp" {
+		t.Errorf("got %s %+v, want an error log from mcp", msg.Method, params)
+	}
+	if data, _ := params.Data.(string); !strings.Contains(data, "handler exploded") {
+		t.Errorf("log data = %v", params.Data)
+	}
+
+	// Without the option, or without the logging capability, nothing is sent
+	for _, opts := range [][]ServerOption{nil, {WithErrorLogging(), WithoutLogging()}} {
+		s := NewServer("test", "1.0", opts...)
+		s.SetFallbackHandler(panicky)
+		c := newTestClient(t, s)
+
+		c.notify("custom/event", nil)
+		pingID := c.request("ping", nil)
+		if msg := c.receive(); string(msg.ID) != string(pingID) {
+			t.Errorf("options %v: got %s before the ping response", opts, msg.Method)
+		}
+	}
+}
+
+func TestHandleMethod(t *testing.T) {
+	s := NewServer("test", "1.0")
+	s.AddTool("echo", "", nil, textTool(func(context.Context, map[string]interface{}) string { return "echo" }))
+
+	// Wrap the built-in tools/call to count calls
+	var calls atomic.Int32
+	builtin := s.LookupMethod("tools/call")
+	if builtin == nil {
+		t.Fatal("no built-in tools/call")
+	}
+	s.HandleMethod("tools/call", func(ctx context.Context, msg *Message) {
+		calls.Add(1)
+		builtin(ctx, msg)
+	})
+
+	// Replace tools/list entirely and add a new method
+	s.HandleMethod("tools/list", func(ctx context.Context, msg *Message) {
+		s.RespondError(ctx, msg.ID, -32601, "Listing disabled")
+	})
+	s.HandleMethod("custom/time", func(ctx context.Context, msg *Message) {
+		s.Respond(ctx, msg.ID, map[string]string{"now": "noon"})
+	})
+	c := newTestClient(t, s)
+
+	if got := c.callTool("echo", nil).text(); got != "echo" || calls.Load() != 1 {
+		t.Errorf("wrapped tools/call = %q after %d calls", got, calls.Load())
+	}
+	if e := c.callError("tools/list", nil, -32601); e.Message != "Listing disabled" {
+		t.Errorf("replaced tools/list = %q", e.Message)
+	}
+	var now struct{ Now string }
+	c.result("custom/time", nil, &now)
+	if now.Now != "noon" {
+		t.Errorf("custom/time = %+v", now)
+	}
+
+	s.HandleMethod("custom/time", nil)
+	c.callError("custom/time", nil, -32601)
+}
+
+func TestPing(t *testing.T) {
+	s := NewServer("test", "1.0")
+	c := connect(t, s)
+
+	// The server may ping before the client has initialized
+	type pingResult struct {
+		rtt time.Duration
+		err error
+	}
+	done := make(chan pingResult, 1)
+	go func() {
+		rtt, err := s.Ping(context.Background())
+		done <- pingResult{rtt, err}
+	}()
+	c.answerPings(1)
+	if r := <-done; r.err != nil || r.rtt < 0 {
+		t.Errorf("Ping = %v, %v", r.rtt, r.err)
+	}
+
+	// Clients may ping the server, too
+	var pong struct{}
+	c.result("ping", nil, &pong)
+
+	c.transport.Close()
+	<-s.Done()
+	if _, err := s.Ping(context.Background()); err == nil {
+		t.Error("Ping over a closed transport succeeded")
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	const idle = 50 * time.Millisecond
+	disconnected := make(chan struct{})
+
+	s := NewServer("test", "1.0", WithIdleTimeout(idle))
+	s.OnDisconnect(func() { close(disconnected) })
+	c := newTestClient(t, s)
+
+	// Regular traffic keeps the connection open past the timeout
+	start := time.Now()
+	for time.Since(start) < 3*idle {
+		var pong struct{}
+		c.result("ping", nil, &pong)
+		time.Sleep(idle / 5)
+	}
+	select {
+	case <-disconnected:
+		t.Fatal("active connection closed")
+	default:
+	}
+
+	// A quiet connection is closed
+	select {
+	case <-disconnected:
+	case <-time.After(testTimeout):
+		t.Fatal("idle connection not closed")
+	}
+	<-s.Done()
+}
+
+func TestOnInitialize(t *testing.T) {
+	var received json.RawMessage
+	var sawServer bool
+
+	s := NewServer("test", "1.0")
+	s.OnInitialize(func(ctx context.Context, params json.RawMessage) {
+		received = params
+		_, sawServer = serverFromContext(ctx)
+	})
+	c := connect(t, s)
+
+	params := initializeParams(ProtocolVersion, nil)
+	params["x-vendor"] = map[string]interface{}{"build": "nightly"}
+	var init struct{ ProtocolVersion string }
+	c.result("initialize", params, &init)
+
+	var got struct {
+		XVendor struct{ Build string } `json:"x-vendor"`
+	}
+	if err := json.Unmarshal(received, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.XVendor.Build != "nightly" {
+		t.Errorf("hook received %s", received)
+	}
+	if !sawServer {
+		t.Error("hook context carries no server")
+	}
+
+	// A rejected second initialize does not reach the hook
+	received = nil
+	c.callError("initialize", params, -32600)
+	if received != nil {
+		t.Errorf("hook called for a repeated initialize with %s", received)
+	}
+}
+
+func TestCloseWaitsForReadLoop(t *testing.T) {
+	// A reader that never returns data keeps the loop blocked in Receive
+	r, w := io.Pipe()
+	defer w.Close()
+
+	s := NewServer("test", "1.0")
+	if err := s.Connect(context.Background(), NewStdioTransportWithIO(r, io.Discard)); err != nil {
+		t.Fatal(err)
+	}
+
+	closed := make(chan error, 1)
+	go func() { closed <- s.Close() }()
+	select {
+	case <-closed:
+	case <-time.After(testTimeout):
+		t.Fatal("Close blocked on a quiet stdio reader")
+	}
+
+	// The loop has exited, and recorded why, by the time Close returns
+	if err := s.Err(); err == nil {
+		t.Error("Close returned before the read loop stopped")
+	}
+}
+
+func TestServerRequestDeadline(t *testing.T) {
+	s := NewServer("test", "1.0")
+	c := connect(t, s)
+	c.initializeWith(map[string]interface{}{"elicitation": map[string]interface{}{}})
+	c.notify("notifications/initialized", nil)
+	waitFor(t, "initialized notification", s.ready.Load)
+
+	for name, call := range map[string]func(ctx context.Context) error{
+		"Ping": func(ctx context.Context) error {
+			_, err := s.Ping(ctx)
+			return err
+		},
+		"Elicit": func(ctx context.Context) error {
+			_, err := s.Elicit(ctx, "name?", json.RawMessage(`{"type":"object"}`))
+			return err
+		},
+	} {
+		// The client receives the request but never answers it
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		err := call(ctx)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s = %v, want %v", name, err, context.DeadlineExceeded)
+		}
+		c.receive()
+
+		s.pendingMu.Lock()
+		n := len(s.pending)
+		s.pendingMu.Unlock()
+		if n != 0 {
+			t.Errorf("%s left %d pending requests", name, n)
+		}
+	}
+}
+
+func TestMissingParams(t *testing.T) {
+	c := newTestClient(t, NewServer("test", "1.0"))
+
+	for _, method := range []string{"tools/call", "tools/get", "resources/read", "prompts/get", "logging/setLevel"} {
+		for _, params := range []json.RawMessage{nil, json.RawMessage("null")} {
+			c.nextID++
+			id := json.RawMessage(fmt.Sprint(c.nextID))
+			c.send(&Message{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+
+			resp := c.response(id)
+			if resp.Error == nil || resp.Error.Code != -32602 {
+				t.Errorf("%s with params %q: got %+v, want error -32602", method, params, resp)
+				continue
+			}
+			if want := "Missing params for " + method; resp.Error.Message != want {
+				t.Errorf("%s: message %q, want %q", method, resp.Error.Message, want)
+			}
+		}
+	}
+
+	// initialize needs its params as well
+	c = connect(t, NewServer("test", "1.0"))
+	if e := c.callError("initialize", nil, -32602); !strings.Contains(e.Message, "initialize") {
+		t.Errorf("initialize: message %q", e.Message)
+	}
+}
+
+func TestDebugErrors(t *testing.T) {
+	type debugData struct {
+		ErrorChain []string `json:"errorChain"`
+		Stack      string   `json:"stack"`
+	}
+
+	for _, debug := range []bool{false, true} {
+		var opts []ServerOption
+		if debug {
+			opts = append(opts, WithDebugErrors())
+		}
+		s := NewServer("test", "1.0", opts...)
+		s.AddPrompt("failing", "", nil, func(context.Context, map[string]interface{}) ([]PromptMessage, error) {
+			return nil, fmt.Errorf("rendering: %w", errTest)
+		})
+		s.AddPrompt("panicking", "", nil, func(context.Context, map[string]interface{}) ([]PromptMessage, error) {
+			panic("boom")
+		})
+		c := newTestClient(t, s)
+
+		failed := c.callError("prompts/get", map[string]interface{}{"name": "failing"}, -32603)
+		panicked := c.callError("prompts/get", map[string]interface{}{"name": "panicking"}, -32603)
+		if !debug {
+			if failed.Data != nil || panicked.Data != nil {
+				t.Errorf("production errors carry data: %s, %s", failed.Data, panicked.Data)
+			}
+			continue
+		}
+
+		var data debugData
+		if err := json.Unmarshal(failed.Data, &data); err != nil {
+			t.Fatalf("decoding error data %s: %v", failed.Data, err)
+		}
+		if want := []string{"rendering: " + errTest.Error(), errTest.Error()}; !slices.Equal(data.ErrorChain, want) || data.Stack != "" {
+			t.Errorf("handler error data = %+v, want chain %q and no stack", data, want)
+		}
+
+		data = debugData{}
+		if err := json.Unmarshal(panicked.Data, &data); err != nil {
+			t.Fatalf("decoding error data %s: %v", panicked.Data, err)
+		}
+		if !slices.Equal(data.ErrorChain, []string{"boom"}) || !strings.Contains(data.Stack, "goroutine") {
+			t.Errorf("panic error data = %+v, want a stack trace", data)
+		}
+	}
+}
+
+func TestWithCapabilities(t *testing.T) {
+	subscribe := map[string]interface{}{"resources": map[string]interface{}{"subscribe": true}}
+	s := NewServer("test", "1.0",
+		WithCapabilities(subscribe),
+		WithCapabilities(map[string]interface{}{"resources": map[string]interface{}{"listChanged": true}}),
+		WithExperimentalCapability("a", true),
+		WithExperimentalCapability("b", true),
+	)
+
+	capabilities := serverCapabilities(t, s)
+	resources, _ := capabilities["resources"].(map[string]interface{})
+	if resources["subscribe"] != true || resources["listChanged"] != true {
+		t.Errorf("resources capability = %v, want subscribe and listChanged", capabilities["resources"])
+	}
+	experimental, _ := capabilities["experimental"].(map[string]interface{})
+	if experimental["a"] != true || experimental["b"] != true {
+		t.Errorf("experimental capability = %v", capabilities["experimental"])
+	}
+	if _, ok := capabilities["tools"]; !ok {
+		t.Error("merging dropped the tools capability")
+	}
+
+	// Merging copies objects rather than sharing the caller's
+	if inner := subscribe["resources"].(map[string]interface{}); len(inner) != 1 {
+		t.Errorf("caller's map was modified: %v", inner)
+	}
+}
@@ -0,0 +1,89 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Spec is a declarative description of a server. Handlers are referenced by
+// name and resolved against a HandlerRegistry when the server is built.
+type Spec struct {
+	Name      string         `json:"name"`
+	Version   string         `json:"version"`
+	Tools     []ToolSpec     `json:"tools,omitempty"`
+	Resources []ResourceSpec `json:"resources,omitempty"`
+}
+
+// ToolSpec describes a tool in a Spec
+type ToolSpec struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+	Handler     string          `json:"handler"`
+}
+
+// ResourceSpec describes a resource in a Spec. A URI containing {param}
+// placeholders is registered as a resource template.
+type ResourceSpec struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MIMEType    string `json:"mimeType,omitempty"`
+	Handler     string `json:"handler"`
+}
+
+// HandlerRegistry maps the handler names used in a Spec to implementations
+type HandlerRegistry struct {
+	Tools             map[string]ToolHandler
+	Resources         map[string]ResourceHandler
+	ResourceTemplates map[string]ResourceTemplateHandler
+}
+
+// NewServerFromSpec creates a server from a JSON manifest, registering each
+// tool and resource it declares with the named handler from registry. It
+// returns an error if the manifest is invalid or references a handler that
+// is not in the registry.
+func NewServerFromSpec(manifest []byte, registry HandlerRegistry) (*Server, error) {
+	var spec Spec
+	if err := json.Unmarshal(manifest, &spec); err != nil {
+		return nil, fmt.Errorf("parsing spec: %w", err)
+	}
+
+	server := NewServer(spec.Name, spec.Version)
+
+	for _, tool := range spec.Tools {
+		handler, ok := registry.Tools[tool.Handler]
+		if !ok {
+			return nil, fmt.Errorf("tool %q: unknown handler %q", tool.Name, tool.Handler)
+		}
+
+		server.AddTool(tool.Name, tool.Description, tool.InputSchema, handler)
+	}
+
+	for _, resource := range spec.Resources {
+		if !strings.Contains(resource.URI, "{") {
+			handler, ok := registry.Resources[resource.Handler]
+			if !ok {
+				return nil, fmt.Errorf("resource %q: unknown handler %q", resource.URI, resource.Handler)
+			}
+
+			server.AddResource(resource.URI, resource.Name, resource.Description, resource.MIMEType, handler)
+			continue
+		}
+
+		handler, ok := registry.ResourceTemplates[resource.Handler]
+		if !ok {
+			return nil, fmt.Errorf("resource template %q: unknown handler %q", resource.URI, resource.Handler)
+		}
+
+		template, err := NewResourceTemplate(resource.URI, resource.Description, resource.MIMEType)
+		if err != nil {
+			return nil, fmt.Errorf("resource template %q: %w", resource.URI, err)
+		}
+
+		server.AddResourceTemplate(template, resource.Name, handler)
+	}
+
+	return server, nil
+}
@@ -0,0 +1,83 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testSpec = `{
+	"name": "spec",
+	"version": "2.0",
+	"tools": [{
+		"name": "greet",
+		"description": "Say hello",
+		"inputSchema": {"type": "object", "properties": {"name": {"type": "string"}}},
+		"handler": "greeter"
+	}],
+	"resources": [{
+		"uri": "docs://readme",
+		"name": "readme",
+		"mimeType": "text/plain",
+		"handler": "readme"
+	}]
+}`
+
+func TestNewServerFromSpec(t *testing.T) {
+	registry := HandlerRegistry{
+		Tools: map[string]ToolHandler{
+			"greeter": textTool(func(_ context.Context, args map[string]interface{}) string {
+				return "hello " + args["name"].(string)
+			}),
+		},
+		Resources: map[string]ResourceHandler{
+			"readme": textResource("read me"),
+		},
+	}
+
+	s, err := NewServerFromSpec([]byte(testSpec), registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := connect(t, s)
+	resp := c.initialize()
+
+	var init struct {
+		ServerInfo struct{ Name, Version string }
+	}
+	if err := json.Unmarshal(resp.Result, &init); err != nil {
+		t.Fatal(err)
+	}
+	if init.ServerInfo.Name != "spec" || init.ServerInfo.Version != "2.0" {
+		t.Errorf("serverInfo = %+v", init.ServerInfo)
+	}
+
+	var tools struct{ Tools []Tool }
+	c.result("tools/list", nil, &tools)
+	if len(tools.Tools) != 1 || tools.Tools[0].Name != "greet" || tools.Tools[0].Description != "Say hello" {
+		t.Errorf("tools = %+v", tools.Tools)
+	}
+	if got := c.callTool("greet", map[string]interface{}{"name": "spec"}).text(); got != "hello spec" {
+		t.Errorf("greet = %q", got)
+	}
+
+	resources := c.listResources()
+	if len(resources) != 1 || resources[0].URI != "docs://readme" || resources[0].MIMEType != "text/plain" {
+		t.Errorf("resources = %+v", resources)
+	}
+	if got := c.readResource("docs://readme").Text; got != "read me" {
+		t.Errorf("readme = %q", got)
+	}
+}
+
+func TestNewServerFromSpecUnknownHandler(t *testing.T) {
+	_, err := NewServerFromSpec([]byte(testSpec), HandlerRegistry{})
+	if err == nil || !strings.Contains(err.Error(), `unknown handler "greeter"`) {
+		t.Errorf("got %v, want an unknown handler error", err)
+	}
+
+	if _, err := NewServerFromSpec([]byte(`{"tools": [`), HandlerRegistry{}); err == nil {
+		t.Error("accepted a truncated manifest")
+	}
+}
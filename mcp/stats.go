@@ -0,0 +1,107 @@
+package mcp
+
+import "sync"
+
+// Stats is a snapshot of a server's traffic counters since it was created
+type Stats struct {
+	// Requests is the number of requests received, excluding notifications
+	// and responses
+	Requests uint64
+
+	// Methods counts requests by method. Methods with no registered handler
+	// are counted under "unknown" so that clients cannot grow the map.
+	Methods map[string]uint64
+
+	// InFlight is the number of message handlers running now
+	InFlight int
+
+	// Errors is the number of error responses sent
+	Errors uint64
+
+	// BytesSent and BytesReceived approximate message sizes by the encoded
+	// length of their ID, method, params, result and error. They do not
+	// include the JSON-RPC envelope or any transport framing.
+	BytesSent     uint64
+	BytesReceived uint64
+}
+
+// serverStats accumulates the counters reported by Stats
+type serverStats struct {
+	mu            sync.Mutex
+	requests      uint64
+	methods       map[string]uint64
+	errors        uint64
+	bytesSent     uint64
+	bytesReceived uint64
+}
+
+// Stats returns a snapshot of the server's traffic counters
+func (s *Server) Stats() Stats {
+	s.inflightMu.Lock()
+	inflight := s.inflight
+	s.inflightMu.Unlock()
+
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+
+	methods := make(map[string]uint64, len(s.stats.methods))
+	for method, n := range s.stats.methods {
+		methods[method] = n
+	}
+
+	return Stats{
+		Requests:      s.stats.requests,
+		Methods:       methods,
+		InFlight:      inflight,
+		Errors:        s.stats.errors,
+		BytesSent:     s.stats.bytesSent,
+		BytesReceived: s.stats.bytesReceived,
+	}
+}
+
+// recordReceived counts an inbound message
+func (s *Server) recordReceived(msg *Message) {
+	method := ""
+	if msg.ID != nil && msg.Method != "" {
+		s.mu.RLock()
+		_, known := s.methods[msg.Method]
+		s.mu.RUnlock()
+
+		method = msg.Method
+		if !known {
+			method = "unknown"
+		}
+	}
+
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+
+	s.stats.bytesReceived += messageSize(msg)
+	if method != "" {
+		if s.stats.methods == nil {
+			s.stats.methods = make(map[string]uint64)
+		}
+		s.stats.requests++
+		s.stats.methods[method]++
+	}
+}
+
+// recordSent counts an outbound message
+func (s *Server) recordSent(msg *Message) {
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+
+	s.stats.bytesSent += messageSize(msg)
+	if msg.Error != nil && msg.Method == "" {
+		s.stats.errors++
+	}
+}
+
+// messageSize approximates the encoded size of msg for Stats
+func messageSize(msg *Message) uint64 {
+	n := len(msg.ID) + len(msg.Method) + len(msg.Params) + len(msg.Result)
+	if msg.Error != nil {
+		n += len(msg.Error.Message) + len(msg.Error.Data)
+	}
+	return uint64(n)
+}
@@ -0,0 +1,60 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	release := make(chan struct{})
+	s := NewServer("test", "1.0")
+	s.AddTool("echo", "", nil, textTool(func(context.Context, map[string]interface{}) string {
+		return "ok"
+	}))
+	s.AddTool("block", "", nil, textTool(func(context.Context, map[string]interface{}) string {
+		<-release
+		return "released"
+	}))
+	c := newTestClient(t, s)
+	waitFor(t, "initialized notification", func() bool { return s.Stats().InFlight == 0 })
+
+	before := s.Stats()
+	if before.Requests != 1 || before.Methods["initialize"] != 1 || before.BytesReceived == 0 || before.BytesSent == 0 {
+		t.Errorf("stats after initialize = %+v", before)
+	}
+
+	c.callTool("echo", nil)
+	c.callTool("echo", nil)
+	c.callError("tools/call", map[string]interface{}{"name": "missing"}, -32602)
+	c.callError("no/such/method", nil, -32601)
+	c.callError("other/missing", nil, -32601)
+
+	after := s.Stats()
+	if got := after.Requests - before.Requests; got != 5 {
+		t.Errorf("requests grew by %d, want 5", got)
+	}
+	if after.Methods["tools/call"] != 3 || after.Methods["unknown"] != 2 {
+		t.Errorf("methods = %v", after.Methods)
+	}
+	if _, ok := after.Methods["no/such/method"]; ok {
+		t.Error("unregistered method counted by name")
+	}
+	if got := after.Errors - before.Errors; got != 3 {
+		t.Errorf("errors grew by %d, want 3", got)
+	}
+	if after.BytesReceived <= before.BytesReceived || after.BytesSent <= before.BytesSent {
+		t.Errorf("byte counts did not grow: %+v then %+v", before, after)
+	}
+
+	// The snapshot does not change with the server
+	after.Methods["tools/call"] = 100
+	if s.Stats().Methods["tools/call"] != 3 {
+		t.Error("modifying a snapshot changed the server's counts")
+	}
+
+	id := c.request("tools/call", map[string]interface{}{"name": "block"})
+	waitFor(t, "blocked handler", func() bool { return s.Stats().InFlight == 1 })
+	close(release)
+	c.response(id)
+	waitFor(t, "handler to finish", func() bool { return s.Stats().InFlight == 0 })
+}
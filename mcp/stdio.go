@@ -3,43 +3,79 @@ package mcp
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"os"
 	"sync"
 )
 
 // StdioTransport implements the Transport interface using standard input/output
 type StdioTransport struct {
+	in        io.Reader
 	reader    *bufio.Reader
 	writer    *bufio.Writer
 	writeLock sync.Mutex
+	encoder   *json.Encoder
+
+	// Incoming lines are read on a separate goroutine so that Receive can
+	// honor context cancellation and Close
+	readOnce sync.Once
+	lines    chan []byte
+	readErr  error
+	done     chan struct{}
+	closed   sync.Once
 }
 
 // NewStdioTransport creates a new stdio transport
 func NewStdioTransport() *StdioTransport {
+	return NewStdioTransportWithIO(os.Stdin, os.Stdout)
+}
+
+// NewStdioTransportWithIO creates a new stdio transport that reads from in
+// and writes to out instead of the process's standard input/output
+func NewStdioTransportWithIO(in io.Reader, out io.Writer) *StdioTransport {
+	writer := bufio.NewWriter(out)
+
 	return &StdioTransport{
-		reader: bufio.NewReader(os.Stdin),
-		writer: bufio.NewWriter(os.Stdout),
+		in:      in,
+		reader:  bufio.NewReader(in),
+		writer:  writer,
+		encoder: json.NewEncoder(writer),
+		lines:   make(chan []byte),
+		done:    make(chan struct{}),
 	}
 }
 
+// SetEscapeHTML specifies whether problematic HTML characters (<, >, &) in
+// outgoing messages are escaped as \u003c, \u003e and \u0026. The default is
+// true, matching json.Marshal.
+func (t *StdioTransport) SetEscapeHTML(on bool) {
+	t.writeLock.Lock()
+	defer t.writeLock.Unlock()
+
+	t.encoder.SetEscapeHTML(on)
+}
+
+// Kind returns "stdio"
+func (t *StdioTransport) Kind() string {
+	return "stdio"
+}
+
 // Send transmits a message through the transport
 func (t *StdioTransport) Send(ctx context.Context, msg *Message) error {
 	t.writeLock.Lock()
 	defer t.writeLock.Unlock()
 
-	data, err := json.Marshal(msg)
-	if err != nil {
-		return err
+	select {
+	case <-t.done:
+		return ErrTransportClosed
+	default:
 	}
 
-	// Write message
-	if _, err := t.writer.Write(data); err != nil {
-		return err
-	}
-
-	if _, err := t.writer.Write([]byte("\n")); err != nil {
+	// Write message; the encoder terminates it with a newline
+	if err := t.encoder.Encode(msg); err != nil {
 		return err
 	}
 
@@ -48,21 +84,60 @@ func (t *StdioTransport) Send(ctx context.Context, msg *Message) error {
 
 // Receive waits for and returns the next incoming message
 func (t *StdioTransport) Receive(ctx context.Context) (*Message, error) {
-	data, err := t.reader.ReadBytes('\n')
-	if err != nil {
-		return nil, err
-	}
+	t.readOnce.Do(func() { go t.readLines() })
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-t.done:
+		return nil, ErrTransportClosed
+	case data, ok := <-t.lines:
+		if !ok {
+			return nil, t.readErr
+		}
 
-	var msg Message
-	if err := json.Unmarshal(data, &msg); err != nil {
-		return nil, err
+		return ParseMessage(data)
 	}
+}
 
-	return &msg, nil
+// readLines reads newline-delimited messages until the input fails or the
+// transport is closed
+func (t *StdioTransport) readLines() {
+	for {
+		data, err := t.reader.ReadBytes('\n')
+		if err != nil {
+			// A final message may lack its trailing newline
+			if err == io.EOF && len(bytes.TrimSpace(data)) > 0 {
+				select {
+				case t.lines <- data:
+				case <-t.done:
+					return
+				}
+			}
+
+			t.readErr = err
+			close(t.lines)
+			return
+		}
+
+		select {
+		case t.lines <- data:
+		case <-t.done:
+			return
+		}
+	}
 }
 
 // Close terminates the transport connection
 func (t *StdioTransport) Close() error {
-	return nil // Nothing to close for stdio
-}
+	var err error
+	t.closed.Do(func() {
+		close(t.done)
 
+		// Closing the input unblocks a pending read
+		if c, ok := t.in.(io.Closer); ok {
+			err = c.Close()
+		}
+	})
+	return err
+}
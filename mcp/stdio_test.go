@@ -0,0 +1,133 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestStdioEscapeHTML(t *testing.T) {
+	params, err := marshalJSON(map[string]string{"data": "<div>&</div>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &Message{JSONRPC: "2.0", Method: "notifications/message", Params: params}
+
+	var out bytes.Buffer
+	transport := NewStdioTransportWithIO(strings.NewReader(""), &out)
+	if err := transport.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `\u003cdiv\u003e`) {
+		t.Errorf("default output %q is not escaped", out.String())
+	}
+
+	out.Reset()
+	transport.SetEscapeHTML(false)
+	if err := transport.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); !strings.Contains(got, "<div>&</div>") || strings.Contains(got, `\u003c`) {
+		t.Errorf("output with escaping off = %q", got)
+	}
+
+	// Messages stay newline framed
+	if got := out.String(); strings.Count(got, "\n") != 1 || !strings.HasSuffix(got, "\n") {
+		t.Errorf("output %q is not one line", got)
+	}
+}
+
+// serveScript runs s over a stdio transport that reads the given lines,
+// and returns the messages the server wrote once the input is exhausted
+func serveScript(t *testing.T, s *Server, lines ...string) []*Message {
+	t.Helper()
+
+	var out bytes.Buffer
+	transport := NewStdioTransportWithIO(strings.NewReader(strings.Join(lines, "\n")), &out)
+	if err := s.ConnectManual(context.Background(), transport); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Serve(context.Background()); err != io.EOF {
+		t.Fatalf("Serve = %v, want io.EOF", err)
+	}
+
+	var messages []*Message
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var msg Message
+		if err := dec.Decode(&msg); err != nil {
+			t.Fatalf("decoding output: %v", err)
+		}
+		messages = append(messages, &msg)
+	}
+	return messages
+}
+
+// scriptInitialize are the input lines of an initialize handshake
+var scriptInitialize = []string{
+	`{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"protocolVersion":"` + ProtocolVersion + `","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`,
+	`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+}
+
+func TestTransportKind(t *testing.T) {
+	kindTool := textTool(func(ctx context.Context, _ map[string]interface{}) string {
+		return TransportKind(ctx)
+	})
+
+	s := NewServer("test", "1.0")
+	s.AddTool("kind", "", nil, kindTool)
+	out := serveScript(t, s, append(scriptInitialize, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"kind"}}`)...)
+	var result toolResult
+	if err := json.Unmarshal(out[len(out)-1].Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.text() != "stdio" {
+		t.Errorf("kind over stdio = %q", result.text())
+	}
+
+	s = NewServer("test", "1.0")
+	s.AddTool("kind", "", nil, kindTool)
+	if got := newTestClient(t, s).callTool("kind", nil).text(); got != "memory" {
+		t.Errorf("kind in memory = %q", got)
+	}
+
+	// A transport that does not report its kind leaves it empty
+	a, b := NewInMemoryTransportPair()
+	s = NewServer("test", "1.0")
+	s.AddTool("kind", "", nil, kindTool)
+	if err := s.Connect(context.Background(), struct{ Transport }{a}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	c := &testClient{t: t, server: s, transport: b}
+	c.initialize()
+	if got := c.callTool("kind", nil).text(); got != "" {
+		t.Errorf("kind of an anonymous transport = %q", got)
+	}
+}
+
+func TestStdioPartialReads(t *testing.T) {
+	input := `{"jsonrpc":"2.0","id":1,"method":"ping"}` + "\n" + `{"jsonrpc":"2.0","id":2,"method":"ping"}`
+
+	// Deliver the input a byte at a time, ending without a newline
+	transport := NewStdioTransportWithIO(iotest.OneByteReader(strings.NewReader(input)), io.Discard)
+	defer transport.Close()
+	ctx := context.Background()
+
+	for _, id := range []string{"1", "2"} {
+		msg, err := transport.Receive(ctx)
+		if err != nil {
+			t.Fatalf("receiving message %s: %v", id, err)
+		}
+		if string(msg.ID) != id || msg.Method != "ping" {
+			t.Errorf("got %s %s, want ping %s", msg.Method, msg.ID, id)
+		}
+	}
+	if _, err := transport.Receive(ctx); err != io.EOF {
+		t.Errorf("after the last message: got %v, want io.EOF", err)
+	}
+}
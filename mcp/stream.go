@@ -0,0 +1,107 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// ResourceStreamHandler is a function that handles resource read requests by
+// returning the resource body as a stream. If the reader is also an
+// io.Closer it is closed once the body has been delivered.
+type ResourceStreamHandler func(ctx context.Context, uri *url.URL) (io.Reader, error)
+
+// ResourceStreamer is implemented by transports that can deliver a resource
+// read result incrementally rather than as one message, such as
+// ChunkedTransport. The transport frames body as messages of its choosing
+// and must finish with the response for request id, or an error response if
+// body fails. content carries the resource metadata; its Text and Blob are
+// empty. Every message must go out through send, which applies the server's
+// send hook, send limit and traffic counters just as for any other message.
+type ResourceStreamer interface {
+	StreamResource(ctx context.Context, id json.RawMessage, content ResourceContent, body io.Reader, send func(context.Context, *Message) error) error
+}
+
+// resourceStream is a registered streaming resource
+type resourceStream struct {
+	handler  ResourceStreamHandler
+	mimeType string
+}
+
+// AddResourceStream registers a static resource whose contents are produced
+// as a stream, for resources too large to hold in memory comfortably. On
+// transports implementing ResourceStreamer, such as ChunkedTransport, the
+// body is streamed; on others, such as stdio, it is read fully and sent as a
+// single response.
+func (s *Server) AddResourceStream(uri, name, description, mimeType string, handler ResourceStreamHandler, opts ...ResourceOption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resource := Resource{
+		URI:         uri,
+		Name:        name,
+		Description: description,
+		MIMEType:    mimeType,
+	}
+	for _, opt := range opts {
+		opt(&resource)
+	}
+
+	// Register the resource
+	s.resources = append(s.resources, resource)
+	s.resourceStreams[uri] = resourceStream{
+		handler:  handler,
+		mimeType: mimeType,
+	}
+}
+
+// readResourceStream serves a resources/read request from a stream handler
+func (s *Server) readResourceStream(ctx context.Context, id json.RawMessage, uri *url.URL, stream resourceStream) {
+	body, err := stream.handler(ctx, uri)
+	if err != nil {
+		s.sendReadError(ctx, id, err)
+		return
+	}
+	if c, ok := body.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	content := ResourceContent{
+		URI:      s.absoluteURI(uri.String()),
+		MIMEType: stream.mimeType,
+	}
+
+	transport, err := s.getTransport()
+	if err != nil {
+		return
+	}
+
+	if streamer, ok := transport.(ResourceStreamer); ok {
+		if err := streamer.StreamResource(ctx, id, content, body, s.sendStreamed); err != nil {
+			s.reportError(ctx, fmt.Errorf("streaming resource: %w", err))
+		}
+		return
+	}
+
+	// Fall back to buffering the whole body
+	data, err := io.ReadAll(body)
+	if err != nil {
+		s.sendReadError(ctx, id, err)
+		return
+	}
+	if isTextMIMEType(stream.mimeType) {
+		content.Text = string(data)
+	} else {
+		content.Blob = data
+	}
+
+	result := struct {
+		Contents []ResourceContent `json:"contents"`
+	}{
+		Contents: []ResourceContent{content},
+	}
+
+	s.sendResult(ctx, id, result)
+}
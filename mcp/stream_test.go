@@ -0,0 +1,131 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// streamBody returns a stream handler serving data
+func streamBody(data []byte) ResourceStreamHandler {
+	return func(context.Context, *url.URL) (io.Reader, error) {
+		return bytes.NewReader(data), nil
+	}
+}
+
+// connectChunked connects s through a ChunkedTransport and initializes
+func connectChunked(t *testing.T, s *Server, chunkSize int) *testClient {
+	t.Helper()
+
+	a, b := NewInMemoryTransportPair()
+	if err := s.Connect(context.Background(), NewChunkedTransport(a, chunkSize)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	c := &testClient{t: t, server: s, transport: b}
+	c.initialize()
+	return c
+}
+
+// readChunked reads uri and reassembles its chunks
+func (c *testClient) readChunked(uri string) (ResourceContent, []byte, int) {
+	c.t.Helper()
+
+	id := c.request("resources/read", map[string]interface{}{"uri": uri})
+
+	var data []byte
+	chunks := 0
+	for {
+		msg := c.receive()
+		if msg.Method == "notifications/resources/chunk" {
+			var chunk ResourceChunk
+			if err := json.Unmarshal(msg.Params, &chunk); err != nil {
+				c.t.Fatal(err)
+			}
+			if string(chunk.RequestID) != string(id) || chunk.Index != chunks {
+				c.t.Fatalf("chunk %d for request %s out of order", chunk.Index, chunk.RequestID)
+			}
+			data = append(data, chunk.Data...)
+			chunks++
+			continue
+		}
+		if msg.Method != "" || string(msg.ID) != string(id) {
+			continue
+		}
+
+		if msg.Error != nil {
+			c.t.Fatalf("resources/read: %s", msg.Error.Message)
+		}
+		var result chunkedReadResult
+		if err := json.Unmarshal(msg.Result, &result); err != nil {
+			c.t.Fatal(err)
+		}
+		if result.Meta.Chunks != chunks {
+			c.t.Fatalf("response reports %d chunks, received %d", result.Meta.Chunks, chunks)
+		}
+		return result.Contents[0], data, chunks
+	}
+}
+
+func TestStreamedResourceChunks(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1<<16) // 1MB
+
+	var hooked atomic.Int32
+	s := NewServer("test", "1.0", WithSendLimit(1))
+	s.AddResourceStream("blob://big", "big", "", "application/octet-stream", streamBody(data))
+	s.OnSend(func(msg *Message) (*Message, error) {
+		if msg.Method == "notifications/resources/chunk" {
+			hooked.Add(1)
+		}
+		return msg, nil
+	})
+	c := connectChunked(t, s, 64<<10)
+
+	before := s.Stats().BytesSent
+	content, got, chunks := c.readChunked("blob://big")
+
+	if !bytes.Equal(got, data) {
+		t.Fatalf("reassembled %d bytes, want %d", len(got), len(data))
+	}
+	if chunks != 16 {
+		t.Errorf("got %d chunks, want 16", chunks)
+	}
+	if content.URI != "blob://big" || content.MIMEType != "application/octet-stream" || content.Blob != nil {
+		t.Errorf("response content = %+v", content)
+	}
+	if n := hooked.Load(); n != 16 {
+		t.Errorf("send hook saw %d chunks, want 16", n)
+	}
+	if sent := s.Stats().BytesSent - before; sent < uint64(len(data)) {
+		t.Errorf("Stats counted %d bytes sent, want at least %d", sent, len(data))
+	}
+}
+
+func TestStreamedResourceBuffered(t *testing.T) {
+	s := NewServer("test", "1.0")
+	s.AddResourceStream("text://doc", "doc", "", "text/plain", streamBody([]byte("all at once")))
+	c := newTestClient(t, s)
+
+	if content := c.readResource("text://doc"); content.Text != "all at once" {
+		t.Errorf("got %q", content.Text)
+	}
+}
+
+func TestStreamedResourceReadError(t *testing.T) {
+	s := NewServer("test", "1.0")
+	s.AddResourceStream("blob://broken", "broken", "", "", func(context.Context, *url.URL) (io.Reader, error) {
+		return io.MultiReader(strings.NewReader("partial"), failingReader{io.ErrClosedPipe}), nil
+	})
+	c := connectChunked(t, s, 4)
+
+	id := c.request("resources/read", map[string]interface{}{"uri": "blob://broken"})
+	if resp := c.response(id); resp.Error == nil || resp.Error.Code != -32603 {
+		t.Errorf("got %+v, want -32603", resp)
+	}
+}
@@ -0,0 +1,140 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	contextType     = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType       = reflect.TypeOf((*error)(nil)).Elem()
+	toolContentType = reflect.TypeOf([]ToolContent(nil))
+)
+
+// RegisterToolFunc registers an ordinary Go function as a tool, deriving the
+// input schema from its parameters. Go does not record parameter names at
+// run time, so they are given in paramNames, one per parameter after the
+// context:
+//
+//	server.RegisterToolFunc("calculate", "Perform a calculation",
+//		func(ctx context.Context, a, b float64, op string) (string, error) {
+//			...
+//		}, "a", "b", "op")
+//
+// The function must take a context.Context first and return either
+// (string, error) or ([]ToolContent, error). Supported parameter kinds are
+// string, bool, the integer and floating-point types, and slices, maps and
+// structs, which are decoded from JSON arrays and objects. Pointer
+// parameters are optional and receive nil when the argument is absent; all
+// others are required.
+func (s *Server) RegisterToolFunc(name, description string, fn interface{}, paramNames ...string) error {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	if t.Kind() != reflect.Func {
+		return fmt.Errorf("mcp: RegisterToolFunc requires a func, got %s", t)
+	}
+	if t.NumIn() == 0 || t.In(0) != contextType {
+		return errors.New("mcp: tool func must take a context.Context first")
+	}
+	if t.NumIn()-1 != len(paramNames) {
+		return fmt.Errorf("mcp: tool func has %d parameters but %d names were given", t.NumIn()-1, len(paramNames))
+	}
+	if t.NumOut() != 2 || (t.Out(0).Kind() != reflect.String && t.Out(0) != toolContentType) || t.Out(1) != errorType {
+		return errors.New("mcp: tool func must return (string, error) or ([]ToolContent, error)")
+	}
+
+	properties := make(map[string]interface{}, len(paramNames))
+	required := make([]string, 0, len(paramNames))
+	for i, name := range paramNames {
+		typ := t.In(i + 1)
+		schemaType, ok := jsonSchemaType(typ)
+		if !ok {
+			return fmt.Errorf("mcp: unsupported type %s for parameter %q", typ, name)
+		}
+		properties[name] = map[string]interface{}{"type": schemaType}
+		if typ.Kind() != reflect.Ptr {
+			required = append(required, name)
+		}
+	}
+
+	schema, err := json.Marshal(map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.AddTool(name, description, schema, func(ctx context.Context, args map[string]interface{}) ([]ToolContent, error) {
+		in := make([]reflect.Value, t.NumIn())
+		in[0] = reflect.ValueOf(ctx)
+		for i, name := range paramNames {
+			typ := t.In(i + 1)
+			arg, ok := args[name]
+			if !ok || arg == nil {
+				if typ.Kind() != reflect.Ptr {
+					return nil, fmt.Errorf("missing required argument %q", name)
+				}
+				in[i+1] = reflect.Zero(typ)
+				continue
+			}
+
+			ptr := reflect.New(typ)
+			if err := bindArgument(arg, ptr.Interface()); err != nil {
+				return nil, fmt.Errorf("argument %q: %w", name, err)
+			}
+			in[i+1] = ptr.Elem()
+		}
+
+		out := v.Call(in)
+		if err, _ := out[1].Interface().(error); err != nil {
+			return nil, err
+		}
+		if content, ok := out[0].Interface().([]ToolContent); ok {
+			return content, nil
+		}
+		return []ToolContent{{Type: "text", Text: out[0].String()}}, nil
+	})
+
+	return nil
+}
+
+// jsonSchemaType returns the JSON Schema type for a Go parameter type
+func jsonSchemaType(t reflect.Type) (string, bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "string", true
+	case reflect.Bool:
+		return "boolean", true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer", true
+	case reflect.Float32, reflect.Float64:
+		return "number", true
+	case reflect.Slice, reflect.Array:
+		return "array", true
+	case reflect.Map, reflect.Struct:
+		return "object", true
+	default:
+		return "", false
+	}
+}
+
+// bindArgument decodes a single loosely typed argument into the value
+// pointed to by v
+func bindArgument(arg interface{}, v interface{}) error {
+	data, err := json.Marshal(arg)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
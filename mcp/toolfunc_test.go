@@ -0,0 +1,86 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestRegisterToolFunc(t *testing.T) {
+	s := NewServer("test", "1.0")
+	err := s.RegisterToolFunc("calculate", "Perform a calculation",
+		func(_ context.Context, a, b float64, op string, precision *int) (string, error) {
+			var result float64
+			switch op {
+			case "add":
+				result = a + b
+			case "divide":
+				if b == 0 {
+					return "", fmt.Errorf("division by zero")
+				}
+				result = a / b
+			}
+			if precision == nil {
+				return fmt.Sprint(result), nil
+			}
+			return fmt.Sprintf("%.*f", *precision, result), nil
+		}, "a", "b", "op", "precision")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newTestClient(t, s)
+
+	var got struct{ Tool Tool }
+	c.result("tools/get", map[string]interface{}{"name": "calculate"}, &got)
+	var schema struct {
+		Properties map[string]struct{ Type string }
+		Required   []string
+	}
+	if err := json.Unmarshal(got.Tool.InputSchema, &schema); err != nil {
+		t.Fatal(err)
+	}
+	if schema.Properties["a"].Type != "number" || schema.Properties["op"].Type != "string" || schema.Properties["precision"].Type != "integer" {
+		t.Errorf("schema properties = %+v", schema.Properties)
+	}
+	if !slices.Equal(schema.Required, []string{"a", "b", "op"}) {
+		t.Errorf("required = %v", schema.Required)
+	}
+
+	tests := []struct {
+		args    map[string]interface{}
+		want    string
+		isError bool
+	}{
+		{map[string]interface{}{"a": 2, "b": 3, "op": "add"}, "5", false},
+		{map[string]interface{}{"a": 1, "b": 3, "op": "divide", "precision": 2}, "0.33", false},
+		{map[string]interface{}{"a": 1, "b": 0, "op": "divide"}, "Error: division by zero", true},
+		{map[string]interface{}{"a": 1, "op": "add"}, `Error: missing required argument "b"`, true},
+		{map[string]interface{}{"a": "one", "b": 1, "op": "add"}, "", true},
+	}
+	for _, tt := range tests {
+		result := c.callTool("calculate", tt.args)
+		if result.IsError != tt.isError || (tt.want != "" && result.text() != tt.want) {
+			t.Errorf("calculate(%v) = %q, isError %v", tt.args, result.text(), result.IsError)
+		}
+	}
+}
+
+func TestRegisterToolFuncRejects(t *testing.T) {
+	s := NewServer("test", "1.0")
+	for _, tt := range []struct {
+		fn    interface{}
+		names []string
+	}{
+		{"not a func", nil},
+		{func(a int) (string, error) { return "", nil }, []string{"a"}},
+		{func(ctx context.Context, a int) (string, error) { return "", nil }, nil},
+		{func(ctx context.Context) string { return "" }, nil},
+		{func(ctx context.Context, ch chan int) (string, error) { return "", nil }, []string{"ch"}},
+	} {
+		if err := s.RegisterToolFunc("bad", "", tt.fn, tt.names...); err == nil {
+			t.Errorf("registered %T", tt.fn)
+		}
+	}
+}
@@ -1,36 +1,263 @@
 package mcp
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 )
 
-// ToolHandler is a function that handles tool call requests
+// ToolHandler is a function that handles tool call requests. Numeric
+// arguments are passed as json.Number so that large integers keep their
+// precision. A handler that returns nil content and a nil error produces an
+// empty content array, or a -32603 error with WithStrictToolResults.
 type ToolHandler func(ctx context.Context, args map[string]interface{}) ([]ToolContent, error)
 
+// validToolContentTypes are the content types a tool result may contain
+var validToolContentTypes = map[string]bool{
+	"text":     true,
+	"image":    true,
+	"audio":    true,
+	"resource": true,
+}
+
+// ToolError is implemented by errors that carry their own tool result
+// content, such as a machine-readable error code and details. When a tool
+// handler returns a ToolError, its content is sent verbatim as the error
+// result instead of the default "Error: ..." text.
+type ToolError interface {
+	error
+	ToolErrorContent() []ToolContent
+}
+
+// RetryableError marks a tool failure as transient, such as a rate limit or
+// an upstream timeout. The error result's _meta tells the client it may
+// retry, and after how long if RetryAfter is set.
+type RetryableError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// toolResultMeta is the _meta of a tool result
+type toolResultMeta struct {
+	Retryable    bool  `json:"retryable,omitempty"`
+	RetryAfterMs int64 `json:"retryAfterMs,omitempty"`
+}
+
+// ToolCallHook is called after a tool handler returns with the tool name, how
+// long the handler ran, the size in bytes of the serialized result content and
+// whether the call failed
+type ToolCallHook func(name string, dur time.Duration, contentBytes int, isErr bool)
+
+// ArgumentDecoder decodes the raw JSON arguments of a tool call. It lets a
+// tool take full control of decoding, for example to keep numbers as
+// json.Number or accept a field that may be a string or a number.
+type ArgumentDecoder func(raw json.RawMessage) (interface{}, error)
+
+// ToolOption configures optional tool behavior at registration
+type ToolOption func(*toolOptions)
+
+// toolOptions collects the settings applied by ToolOptions
+type toolOptions struct {
+	annotations *ToolAnnotations
+	examples    []json.RawMessage
+	decoder     ArgumentDecoder
+	maxArgBytes int
+	requires    []string
+}
+
+// WithToolAnnotations attaches behavioral hints to a tool
+func WithToolAnnotations(annotations ToolAnnotations) ToolOption {
+	return func(o *toolOptions) {
+		o.annotations = &annotations
+	}
+}
+
+// WithToolExamples attaches example argument objects to a tool. Examples are
+// returned by tools/get but left out of tools/list to keep it small.
+func WithToolExamples(examples ...json.RawMessage) ToolOption {
+	return func(o *toolOptions) {
+		o.examples = append(o.examples, examples...)
+	}
+}
+
+// WithArgumentDecoder decodes the tool's raw arguments with decoder before
+// the handler runs. The handler retrieves the result with DecodedArguments.
+// A decoder error is reported to the client as invalid params.
+func WithArgumentDecoder(decoder ArgumentDecoder) ToolOption {
+	return func(o *toolOptions) {
+		o.decoder = decoder
+	}
+}
+
+// ToolDef describes a tool for AddTools
+type ToolDef struct {
+	Name        string
+	Description string
+	InputSchema json.RawMessage
+	Handler     ToolHandler
+	Options     []ToolOption
+}
+
+// WithMaxArgumentBytes rejects calls to the tool whose raw arguments JSON is
+// longer than n bytes, before the handler runs
+func WithMaxArgumentBytes(n int) ToolOption {
+	return func(o *toolOptions) {
+		o.maxArgBytes = n
+	}
+}
+
+// WithRequiredClientCapabilities rejects calls to the tool unless the client
+// declared each of the named capabilities, such as "sampling" or
+// "elicitation", at initialize
+func WithRequiredClientCapabilities(capabilities ...string) ToolOption {
+	return func(o *toolOptions) {
+		o.requires = append(o.requires, capabilities...)
+	}
+}
+
 // AddTool registers a tool with the server
-func (s *Server) AddTool(name, description string, inputSchema json.RawMessage, handler ToolHandler) {
+func (s *Server) AddTool(name, description string, inputSchema json.RawMessage, handler ToolHandler, opts ...ToolOption) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.addTool(name, description, inputSchema, handler, opts)
+}
+
+// AddTools registers several tools at once. Clients listing tools see
+// either none or all of them, and an initialized client gets a single
+// tools/list_changed notification for the whole batch.
+func (s *Server) AddTools(defs []ToolDef) {
+	if len(defs) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	for _, def := range defs {
+		s.addTool(def.Name, def.Description, def.InputSchema, def.Handler, def.Options)
+	}
+	s.mu.Unlock()
+
+	if s.ready.Load() {
+		s.NotifyToolsChanged(context.Background())
+	}
+}
+
+// addTool registers a tool. The caller must hold s.mu.
+func (s *Server) addTool(name, description string, inputSchema json.RawMessage, handler ToolHandler, opts []ToolOption) {
+	var o toolOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	tool := Tool{
 		Name:        name,
 		Description: description,
 		InputSchema: inputSchema,
+		Annotations: o.annotations,
+		Examples:    o.examples,
 	}
 
 	// Register the tool
 	s.tools = append(s.tools, tool)
+	s.toolsVersion++
+	s.toolsList = nil
 	s.toolHandlers[name] = handler
+	if o.decoder != nil {
+		s.toolDecoders[name] = o.decoder
+	} else {
+		delete(s.toolDecoders, name)
+	}
+	if o.maxArgBytes > 0 {
+		s.toolArgLimits[name] = o.maxArgBytes
+	} else {
+		delete(s.toolArgLimits, name)
+	}
+	if len(o.requires) > 0 {
+		s.toolRequires[name] = o.requires
+	} else {
+		delete(s.toolRequires, name)
+	}
+}
+
+// OnToolCall sets a hook that is called after every tool call, on both the
+// success and error paths
+func (s *Server) OnToolCall(hook ToolCallHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.toolCallHook = hook
 }
 
 // handleListTools handles a tools/list request
 func (s *Server) handleListTools(ctx context.Context, msg *Message) {
-	s.mu.RLock()
-	tools := make([]Tool, len(s.tools))
-	copy(tools, s.tools)
-	s.mu.RUnlock()
+	// Clients may ask for only read-only or only destructive tools
+	var params struct {
+		Filter string `json:"filter"`
+	}
+	if msg.Params != nil {
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			s.sendError(ctx, msg.ID, -32700, "Parse error")
+			return
+		}
+	}
+
+	var match func(Tool) bool
+	switch params.Filter {
+	case "":
+	case "readOnly":
+		match = isReadOnlyTool
+	case "destructive":
+		match = isDestructiveTool
+	default:
+		s.sendError(ctx, msg.ID, -32602, fmt.Sprintf("Unknown filter %q", params.Filter))
+		return
+	}
+
+	// The unfiltered list only changes when tools are registered, so it is
+	// served from a cache. A draining server lists no tools and is not cached.
+	draining := s.draining.Load()
+	cacheable := match == nil && !draining
+	if cacheable {
+		s.mu.RLock()
+		cached := s.toolsList
+		s.mu.RUnlock()
+
+		if cached != nil {
+			s.sendRawResult(ctx, msg.ID, cached)
+			return
+		}
+	}
+
+	// A draining server advertises no tools so clients stop calling them
+	tools := []Tool{}
+	var version uint64
+	if !draining {
+		s.mu.RLock()
+		version = s.toolsVersion
+		for _, tool := range s.tools {
+			// A read-only server hides tools it would refuse to call
+			if s.readOnly && !isReadOnlyTool(tool) {
+				continue
+			}
+			if match != nil && !match(tool) {
+				continue
+			}
+			tool.Examples = nil
+			tools = append(tools, tool)
+		}
+		s.mu.RUnlock()
+	}
 
 	// Build response
 	result := struct {
@@ -39,15 +266,72 @@ func (s *Server) handleListTools(ctx context.Context, msg *Message) {
 		Tools: tools,
 	}
 
+	if !cacheable {
+		s.sendResult(ctx, msg.ID, result)
+		return
+	}
+
+	resultBytes, err := marshalJSON(result)
+	if err != nil {
+		s.sendError(ctx, msg.ID, -32603, "Internal error")
+		return
+	}
+
+	// Keep the result unless a tool was registered while it was built
+	s.mu.Lock()
+	if s.toolsVersion == version {
+		s.toolsList = resultBytes
+	}
+	s.mu.Unlock()
+
+	s.sendRawResult(ctx, msg.ID, resultBytes)
+}
+
+// handleGetTool handles a tools/get request, returning the full definition
+// of a single tool including its examples
+func (s *Server) handleGetTool(ctx context.Context, msg *Message) {
+	if !s.requireParams(ctx, msg) {
+		return
+	}
+
+	// Parse request
+	var params struct {
+		Name string `json:"name"`
+	}
+
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		s.sendError(ctx, msg.ID, -32700, "Parse error")
+		return
+	}
+
+	s.mu.RLock()
+	tool, exists := s.findTool(params.Name)
+	s.mu.RUnlock()
+
+	if !exists || s.draining.Load() || (s.readOnly && !isReadOnlyTool(tool)) {
+		s.sendError(ctx, msg.ID, -32602, "Tool not found")
+		return
+	}
+
+	result := struct {
+		Tool Tool `json:"tool"`
+	}{
+		Tool: tool,
+	}
+
 	s.sendResult(ctx, msg.ID, result)
 }
 
 // handleCallTool handles a tools/call request
 func (s *Server) handleCallTool(ctx context.Context, msg *Message) {
+	if !s.requireParams(ctx, msg) {
+		return
+	}
+
 	// Parse request
 	var params struct {
-		Name      string                 `json:"name"`
-		Arguments map[string]interface{} `json:"arguments"`
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
 	}
 
 	if err := json.Unmarshal(msg.Params, &params); err != nil {
@@ -55,53 +339,272 @@ func (s *Server) handleCallTool(ctx context.Context, msg *Message) {
 		return
 	}
 
+	// Audit every attempt with its final status, including calls rejected
+	// before the handler runs, and only then send the reply so that the
+	// record exists by the time the client sees the outcome
+	var (
+		args  map[string]interface{}
+		reply func()
+	)
+	start := time.Now()
+	auditStatus := AuditStatusError
+	identity := IdentityFromContext(ctx)
+	defer func() {
+		if s.auditLogger != nil {
+			s.auditLogger.RecordToolCall(identity, params.Name, args, auditStatus, start)
+		}
+		if reply != nil {
+			reply()
+		}
+	}()
+
+	if len(params.Arguments) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(params.Arguments))
+		dec.UseNumber()
+		if err := dec.Decode(&args); err != nil {
+			reply = func() { s.sendError(ctx, msg.ID, -32700, "Parse error") }
+			return
+		}
+	}
+
+	if s.draining.Load() {
+		reply = func() {
+			s.sendErrorData(ctx, msg.ID, -32000, "Server is shutting down", map[string]interface{}{
+				"retryable": true,
+			})
+		}
+		return
+	}
+
 	// Find the tool handler
 	s.mu.RLock()
 	handler, exists := s.toolHandlers[params.Name]
+	decoder := s.toolDecoders[params.Name]
+	maxArgBytes := s.toolArgLimits[params.Name]
+	tool, _ := s.findTool(params.Name)
+	missing := ""
+	for _, capability := range s.toolRequires[params.Name] {
+		if _, ok := s.clientCapabilities[capability]; !ok {
+			missing = capability
+			break
+		}
+	}
 	s.mu.RUnlock()
 
 	if !exists {
-		s.sendError(ctx, msg.ID, -32602, "Tool not found")
+		reply = func() { s.sendError(ctx, msg.ID, -32602, "Tool not found") }
+		return
+	}
+
+	if s.readOnly && !isReadOnlyTool(tool) {
+		reply = func() { s.sendError(ctx, msg.ID, -32601, "Tool not available in read-only mode") }
+		return
+	}
+
+	if missing != "" {
+		reply = func() {
+			s.sendError(ctx, msg.ID, -32601, fmt.Sprintf("Tool %q requires the %q client capability", params.Name, missing))
+		}
+		return
+	}
+
+	if maxArgBytes > 0 && len(params.Arguments) > maxArgBytes {
+		reply = func() {
+			s.sendError(ctx, msg.ID, -32602, fmt.Sprintf("Arguments of %d bytes exceed the tool's limit of %d bytes", len(params.Arguments), maxArgBytes))
+		}
 		return
 	}
 
+	if s.coerceArguments {
+		coerceArguments(tool.InputSchema, args)
+	}
+
+	if decoder != nil {
+		decoded, err := decoder(params.Arguments)
+		if err != nil {
+			reply = func() { s.sendHandlerError(ctx, msg.ID, -32602, fmt.Sprintf("Invalid arguments: %v", err), err) }
+			return
+		}
+		ctx = withDecodedArguments(ctx, decoded)
+	}
+
+	// Idempotent tools may answer from a recent identical call
+	var cacheKey string
+	if s.toolResultCache != nil && tool.Annotations != nil && tool.Annotations.IdempotentHint {
+		cacheKey, _ = toolCacheKey(params.Name, args)
+	}
+
 	// Execute the tool
-	content, err := handler(ctx, params.Arguments)
+	ctx = withLoggerName(ctx, params.Name)
+	handlerStart := time.Now()
+	var (
+		content []ToolContent
+		err     error
+		cached  bool
+	)
+	if cacheKey != "" {
+		content, cached = s.toolResultCache.get(cacheKey)
+	}
+	if !cached {
+		content, err = handler(ctx, args)
+		if err == nil && cacheKey != "" {
+			s.toolResultCache.put(cacheKey, content)
+		}
+	}
+	dur := time.Since(handlerStart)
+
+	// Only a request that was itself cancelled or timed out fails with
+	// -32800. A handler reporting its own failure, even an upstream timeout,
+	// gets an error result, and tool errors always keep their content.
+	var (
+		toolErr   ToolError
+		retryable *RetryableError
+	)
+	reported := errors.As(err, &toolErr)
+	if errors.As(err, &retryable) {
+		reported = true
+	}
+	cancelled := ctx.Err() != nil && isCancellation(err) && !reported
+
+	isError := false
 	if err != nil {
 		// Return the error as a tool result with isError flag
-		result := struct {
-			Content []ToolContent `json:"content"`
-			IsError bool          `json:"isError"`
-		}{
-			Content: []ToolContent{{
+		if toolErr != nil {
+			content = toolErr.ToolErrorContent()
+		} else {
+			content = []ToolContent{{
 				Type: "text",
 				Text: fmt.Sprintf("Error: %v", err),
-			}},
-			IsError: true,
+			}}
 		}
+		isError = true
+	}
 
-		s.sendResult(ctx, msg.ID, result)
+	s.mu.RLock()
+	hook := s.toolCallHook
+	s.mu.RUnlock()
+
+	var size int
+	if hook != nil || s.maxToolOutputBytes > 0 {
+		contentBytes, _ := json.Marshal(content)
+		size = len(contentBytes)
+	}
+
+	// Don't flood the client with runaway output
+	if s.maxToolOutputBytes > 0 && size > s.maxToolOutputBytes {
+		content = []ToolContent{{
+			Type: "text",
+			Text: fmt.Sprintf("Error: tool output of %d bytes exceeds the limit of %d bytes", size, s.maxToolOutputBytes),
+		}}
+		isError = true
+	}
+
+	if hook != nil {
+		hook(params.Name, dur, size, isError)
+	}
+
+	// A cancelled request gets no response; the client has given up on it.
+	// A timed-out one reports -32800.
+	if cancelled {
+		auditStatus = AuditStatusCancelled
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			reply = func() { s.sendError(ctx, msg.ID, -32800, "Request timed out") }
+		}
 		return
 	}
 
+	// A handler that returned neither content nor an error still owes the
+	// client a content array
+	if content == nil && !isError {
+		if s.strictToolResults {
+			reply = func() { s.sendError(ctx, msg.ID, -32603, "Tool returned no content") }
+			return
+		}
+		content = []ToolContent{}
+	}
+
+	// Reject content clients won't understand
+	for _, c := range content {
+		if !validToolContentTypes[c.Type] {
+			reply = func() {
+				s.sendError(ctx, msg.ID, -32603, fmt.Sprintf("Tool returned unsupported content type %q", c.Type))
+			}
+			return
+		}
+	}
+
 	// Return the tool result
 	result := struct {
-		Content []ToolContent `json:"content"`
-		IsError bool          `json:"isError"`
+		Content []ToolContent   `json:"content"`
+		IsError bool            `json:"isError"`
+		Meta    *toolResultMeta `json:"_meta,omitempty"`
 	}{
 		Content: content,
-		IsError: false,
+		IsError: isError,
 	}
 
-	s.sendResult(ctx, msg.ID, result)
+	if !isError {
+		auditStatus = AuditStatusOK
+	}
+
+	// Let clients know a transient failure is worth retrying
+	if retryable != nil {
+		result.Meta = &toolResultMeta{
+			Retryable:    true,
+			RetryAfterMs: retryable.RetryAfter.Milliseconds(),
+		}
+	}
+
+	reply = func() { s.sendResult(ctx, msg.ID, result) }
 }
 
-// NotifyToolsChanged sends a notification that the tools list has changed
-func (s *Server) NotifyToolsChanged(ctx context.Context) error {
-	notification := &Message{
-		JSONRPC: "2.0",
-		Method:  "notifications/tools/list_changed",
+// isCancellation reports whether err is due to a cancelled or expired context
+func isCancellation(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// findTool returns the registered tool with the given name. The caller must
+// hold s.mu.
+func (s *Server) findTool(name string) (Tool, bool) {
+	for _, tool := range s.tools {
+		if tool.Name == name {
+			return tool, true
+		}
 	}
+	return Tool{}, false
+}
 
-	return s.transport.Send(ctx, notification)
+// isReadOnlyTool reports whether a tool is annotated as read-only
+func isReadOnlyTool(tool Tool) bool {
+	return tool.Annotations != nil && tool.Annotations.ReadOnlyHint
+}
+
+// isDestructiveTool reports whether a tool may make destructive changes.
+// Tools that are not read-only are destructive unless annotated otherwise.
+func isDestructiveTool(tool Tool) bool {
+	if isReadOnlyTool(tool) {
+		return false
+	}
+	return tool.Annotations == nil || tool.Annotations.DestructiveHint == nil || *tool.Annotations.DestructiveHint
+}
+
+// ToolsByAnnotation returns the registered tools whose read-only hint is
+// readOnly
+func (s *Server) ToolsByAnnotation(readOnly bool) []Tool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var tools []Tool
+	for _, tool := range s.tools {
+		if isReadOnlyTool(tool) == readOnly {
+			tools = append(tools, tool)
+		}
+	}
+
+	return tools
+}
+
+// NotifyToolsChanged sends a notification that the tools list has changed
+func (s *Server) NotifyToolsChanged(ctx context.Context) error {
+	return s.notify(ctx, "notifications/tools/list_changed", nil)
 }
@@ -0,0 +1,721 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestToolCallHook(t *testing.T) {
+	type call struct {
+		name         string
+		dur          time.Duration
+		contentBytes int
+		isErr        bool
+	}
+	var (
+		mu    sync.Mutex
+		calls []call
+	)
+
+	s := NewServer("test", "1.0")
+	s.AddTool("slow", "", nil, textTool(func(context.Context, map[string]interface{}) string {
+		time.Sleep(time.Millisecond)
+		return "done"
+	}))
+	s.AddTool("fail", "", nil, func(context.Context, map[string]interface{}) ([]ToolContent, error) {
+		return nil, errTest
+	})
+	s.OnToolCall(func(name string, dur time.Duration, contentBytes int, isErr bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, call{name, dur, contentBytes, isErr})
+	})
+	c := newTestClient(t, s)
+
+	c.callTool("slow", nil)
+	c.callTool("fail", nil)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) != 2 {
+		t.Fatalf("hook called %d times, want 2", len(calls))
+	}
+	if got := calls[0]; got.name != "slow" || got.dur <= 0 || got.contentBytes == 0 || got.isErr {
+		t.Errorf("successful call: got %+v", got)
+	}
+	if got := calls[1]; got.name != "fail" || got.contentBytes == 0 || !got.isErr {
+		t.Errorf("failed call: got %+v", got)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	s := NewServer("test", "1.0")
+	s.AddTool("slow", "", nil, textTool(func(context.Context, map[string]interface{}) string {
+		close(started)
+		<-release
+		return "finished"
+	}))
+	c := newTestClient(t, s)
+
+	inflight := c.request("tools/call", map[string]interface{}{"name": "slow"})
+	<-started
+	s.Drain()
+
+	var tools struct{ Tools []Tool }
+	c.result("tools/list", nil, &tools)
+	if len(tools.Tools) != 0 {
+		t.Errorf("draining server lists %d tools", len(tools.Tools))
+	}
+
+	rejected := c.callError("tools/call", map[string]interface{}{"name": "slow"}, -32000)
+	var data struct{ Retryable bool }
+	if err := json.Unmarshal(rejected.Data, &data); err != nil || !data.Retryable {
+		t.Errorf("rejection data = %s, want retryable", rejected.Data)
+	}
+
+	// The call already running completes normally
+	close(release)
+	var result toolResult
+	resp := c.response(inflight)
+	if err := json.Unmarshal(resp.Result, &result); err != nil || result.text() != "finished" {
+		t.Errorf("in-flight call: got %s, %v", resp.Result, resp.Error)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown: %v", err)
+	}
+}
+
+// quotaError is a ToolError with a machine-readable payload
+type quotaError struct{ remaining int }
+
+func (e quotaError) Error() string { return "quota exceeded" }
+
+func (e quotaError) ToolErrorContent() []ToolContent {
+	return []ToolContent{{Type: "text", Text: fmt.Sprintf(`{"code":"QUOTA","remaining":%d}`, e.remaining)}}
+}
+
+func TestToolErrorContent(t *testing.T) {
+	s := NewServer("test", "1.0")
+	s.AddTool("quota", "", nil, func(context.Context, map[string]interface{}) ([]ToolContent, error) {
+		return nil, fmt.Errorf("calling upstream: %w", quotaError{remaining: 0})
+	})
+	s.AddTool("plain", "", nil, func(context.Context, map[string]interface{}) ([]ToolContent, error) {
+		return nil, errTest
+	})
+	c := newTestClient(t, s)
+
+	result := c.callTool("quota", nil)
+	if !result.IsError || result.text() != `{"code":"QUOTA","remaining":0}` {
+		t.Errorf("ToolError result = %+v", result)
+	}
+
+	result = c.callTool("plain", nil)
+	if !result.IsError || result.text() != "Error: test failure" {
+		t.Errorf("plain error result = %+v", result)
+	}
+}
+
+func TestUnsupportedContentType(t *testing.T) {
+	s := NewServer("test", "1.0")
+	s.AddTool("bogus", "", nil, func(context.Context, map[string]interface{}) ([]ToolContent, error) {
+		return []ToolContent{{Type: "text", Text: "ok"}, {Type: "bogus"}}, nil
+	})
+	c := newTestClient(t, s)
+
+	e := c.callError("tools/call", map[string]interface{}{"name": "bogus"}, -32603)
+	if !strings.Contains(e.Message, `"bogus"`) {
+		t.Errorf("error message %q does not name the content type", e.Message)
+	}
+}
+
+func TestReadOnlyServer(t *testing.T) {
+	s := NewServer("test", "1.0", WithReadOnly())
+	s.AddTool("read", "", nil, textTool(func(context.Context, map[string]interface{}) string { return "read" }),
+		WithToolAnnotations(ToolAnnotations{ReadOnlyHint: true}))
+	s.AddTool("delete", "", nil, textTool(func(context.Context, map[string]interface{}) string {
+		t.Error("destructive tool ran on a read-only server")
+		return "deleted"
+	}))
+	c := newTestClient(t, s)
+
+	if got := c.callTool("read", nil).text(); got != "read" {
+		t.Errorf("read-only tool = %q", got)
+	}
+	c.callError("tools/call", map[string]interface{}{"name": "delete"}, -32601)
+
+	var tools struct{ Tools []Tool }
+	c.result("tools/list", nil, &tools)
+	if len(tools.Tools) != 1 || tools.Tools[0].Name != "read" {
+		t.Errorf("read-only server lists %+v", tools.Tools)
+	}
+}
+
+func TestCancelledToolCall(t *testing.T) {
+	audit := &fakeAuditLogger{}
+	s := NewServer("test", "1.0", WithRequestTimeout(20*time.Millisecond), WithAuditLogger(audit))
+	s.AddTool("wait", "", nil, func(ctx context.Context, _ map[string]interface{}) ([]ToolContent, error) {
+		<-ctx.Done()
+		return nil, fmt.Errorf("waiting: %w", ctx.Err())
+	})
+	s.AddTool("giveup", "", nil, func(context.Context, map[string]interface{}) ([]ToolContent, error) {
+		return nil, context.Canceled
+	})
+	s.AddTool("upstream", "", nil, func(context.Context, map[string]interface{}) ([]ToolContent, error) {
+		return nil, fmt.Errorf("fetching: %w", context.DeadlineExceeded)
+	})
+	s.AddTool("retry", "", nil, func(ctx context.Context, _ map[string]interface{}) ([]ToolContent, error) {
+		<-ctx.Done()
+		return nil, &RetryableError{Err: ctx.Err(), RetryAfter: time.Second}
+	})
+	c := newTestClient(t, s)
+
+	// A timed-out request is an error, not a failed tool result
+	if e := c.callError("tools/call", map[string]interface{}{"name": "wait"}, -32800); e.Message != "Request timed out" {
+		t.Errorf("timeout message = %q", e.Message)
+	}
+
+	// Handlers that fail with a cancellation of their own, while the request
+	// is still alive, report a failed tool result
+	for _, name := range []string{"giveup", "upstream"} {
+		if result := c.callTool(name, nil); !result.IsError {
+			t.Errorf("%s: got %+v, want an error result", name, result)
+		}
+	}
+
+	// A retryable error keeps its metadata even once the request timed out
+	result := c.callTool("retry", nil)
+	if !result.IsError || string(result.Meta) != `{"retryable":true,"retryAfterMs":1000}` {
+		t.Errorf("retry: got %+v, %s", result, result.Meta)
+	}
+
+	audit.mu.Lock()
+	defer audit.mu.Unlock()
+	var statuses []string
+	for _, call := range audit.calls {
+		statuses = append(statuses, call.Status)
+	}
+	if want := []string{AuditStatusCancelled, AuditStatusError, AuditStatusError, AuditStatusError}; !slices.Equal(statuses, want) {
+		t.Errorf("audit statuses = %q, want %q", statuses, want)
+	}
+}
+
+func TestArgumentDecoder(t *testing.T) {
+	type lookup struct {
+		ID int64 `json:"id"`
+	}
+	decode := func(raw json.RawMessage) (interface{}, error) {
+		var args lookup
+		err := json.Unmarshal(raw, &args)
+		return args, err
+	}
+
+	s := NewServer("test", "1.0")
+	s.AddTool("lookup", "", nil, textTool(func(ctx context.Context, _ map[string]interface{}) string {
+		return fmt.Sprint(DecodedArguments(ctx).(lookup).ID)
+	}), WithArgumentDecoder(decode))
+	c := newTestClient(t, s)
+
+	if got := c.callTool("lookup", json.RawMessage(`{"id": 9007199254740993}`)).text(); got != "9007199254740993" {
+		t.Errorf("decoded id = %s", got)
+	}
+	c.callError("tools/call", map[string]interface{}{"name": "lookup", "arguments": map[string]interface{}{"id": "x"}}, -32602)
+}
+
+func TestArgumentPrecision(t *testing.T) {
+	s := NewServer("test", "1.0")
+	s.AddTool("echo", "", nil, textTool(func(_ context.Context, args map[string]interface{}) string {
+		n, ok := args["id"].(json.Number)
+		if !ok {
+			return fmt.Sprintf("%T", args["id"])
+		}
+		return n.String()
+	}))
+	c := newTestClient(t, s)
+
+	for _, id := range []string{"9007199254740993", "-1", "1.5", "1e3"} {
+		if got := c.callTool("echo", json.RawMessage(`{"id": `+id+`}`)).text(); got != id {
+			t.Errorf("id %s arrived as %s", id, got)
+		}
+	}
+}
+
+func TestGetTool(t *testing.T) {
+	schema := json.RawMessage(`{"type":"object","properties":{"q":{"type":"string"}}}`)
+	example := json.RawMessage(`{"q":"golang"}`)
+
+	s := NewServer("test", "1.0")
+	s.AddTool("search", "Search the web", schema, textTool(func(context.Context, map[string]interface{}) string { return "" }),
+		WithToolExamples(example),
+		WithToolAnnotations(ToolAnnotations{Title: "Search", ReadOnlyHint: true}))
+	c := newTestClient(t, s)
+
+	var got struct{ Tool Tool }
+	c.result("tools/get", map[string]interface{}{"name": "search"}, &got)
+	tool := got.Tool
+	if tool.Name != "search" || tool.Description != "Search the web" || string(tool.InputSchema) != string(schema) {
+		t.Errorf("tools/get = %+v", tool)
+	}
+	if tool.Annotations == nil || tool.Annotations.Title != "Search" {
+		t.Errorf("annotations = %+v", tool.Annotations)
+	}
+	if len(tool.Examples) != 1 || string(tool.Examples[0]) != string(example) {
+		t.Errorf("examples = %s", tool.Examples)
+	}
+
+	// Examples are left out of the list
+	var list struct{ Tools []Tool }
+	c.result("tools/list", nil, &list)
+	if len(list.Tools) != 1 || list.Tools[0].Examples != nil {
+		t.Errorf("tools/list = %+v", list.Tools)
+	}
+
+	c.callError("tools/get", map[string]interface{}{"name": "missing"}, -32602)
+}
+
+func TestToolsByAnnotation(t *testing.T) {
+	noop := textTool(func(context.Context, map[string]interface{}) string { return "" })
+	notDestructive := false
+
+	s := NewServer("test", "1.0")
+	s.AddTool("read", "", nil, noop, WithToolAnnotations(ToolAnnotations{ReadOnlyHint: true}))
+	s.AddTool("write", "", nil, noop, WithToolAnnotations(ToolAnnotations{DestructiveHint: &notDestructive}))
+	s.AddTool("delete", "", nil, noop)
+	s.AddTool("search", "", nil, noop, WithToolAnnotations(ToolAnnotations{ReadOnlyHint: true}))
+
+	names := func(tools []Tool) []string {
+		var names []string
+		for _, tool := range tools {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+
+	if got := names(s.ToolsByAnnotation(true)); !slices.Equal(got, []string{"read", "search"}) {
+		t.Errorf("read-only tools = %v", got)
+	}
+	if got := names(s.ToolsByAnnotation(false)); !slices.Equal(got, []string{"write", "delete"}) {
+		t.Errorf("other tools = %v", got)
+	}
+
+	c := newTestClient(t, s)
+	for filter, want := range map[string][]string{
+		"readOnly":    {"read", "search"},
+		"destructive": {"delete"},
+		"":            {"read", "write", "delete", "search"},
+	} {
+		var list struct{ Tools []Tool }
+		c.result("tools/list", map[string]interface{}{"filter": filter}, &list)
+		if got := names(list.Tools); !slices.Equal(got, want) {
+			t.Errorf("filter %q = %v, want %v", filter, got, want)
+		}
+	}
+	c.callError("tools/list", map[string]interface{}{"filter": "bogus"}, -32602)
+}
+
+func TestMaxToolOutputBytes(t *testing.T) {
+	var hookBytes int
+	var hookErr bool
+
+	s := NewServer("test", "1.0", WithMaxToolOutputBytes(100))
+	s.AddTool("small", "", nil, textTool(func(context.Context, map[string]interface{}) string {
+		return "fits"
+	}))
+	s.AddTool("huge", "", nil, textTool(func(context.Context, map[string]interface{}) string {
+		return strings.Repeat("x", 1000)
+	}))
+	s.OnToolCall(func(_ string, _ time.Duration, contentBytes int, isErr bool) {
+		hookBytes, hookErr = contentBytes, isErr
+	})
+	c := newTestClient(t, s)
+
+	if result := c.callTool("small", nil); result.IsError || result.text() != "fits" {
+		t.Errorf("small output = %+v", result)
+	}
+
+	result := c.callTool("huge", nil)
+	if !result.IsError || !strings.Contains(result.text(), "exceeds the limit of 100 bytes") {
+		t.Errorf("huge output = %q, isError %v", result.text(), result.IsError)
+	}
+	if hookBytes < 1000 || !hookErr {
+		t.Errorf("hook saw %d bytes, isError %v", hookBytes, hookErr)
+	}
+}
+
+func TestRetryableError(t *testing.T) {
+	s := NewServer("test", "1.0")
+	s.AddTool("limited", "", nil, func(context.Context, map[string]interface{}) ([]ToolContent, error) {
+		return nil, &RetryableError{Err: errors.New("rate limited"), RetryAfter: 1500 * time.Millisecond}
+	})
+	s.AddTool("flaky", "", nil, func(context.Context, map[string]interface{}) ([]ToolContent, error) {
+		return nil, fmt.Errorf("upstream: %w", &RetryableError{Err: errTest})
+	})
+	s.AddTool("broken", "", nil, func(context.Context, map[string]interface{}) ([]ToolContent, error) {
+		return nil, errTest
+	})
+	c := newTestClient(t, s)
+
+	tests := []struct {
+		tool string
+		meta string
+	}{
+		{"limited", `{"retryable":true,"retryAfterMs":1500}`},
+		{"flaky", `{"retryable":true}`},
+		{"broken", ``},
+	}
+	for _, tt := range tests {
+		result := c.callTool(tt.tool, nil)
+		if !result.IsError || string(result.Meta) != tt.meta {
+			t.Errorf("%s: isError %v, _meta %s, want %s", tt.tool, result.IsError, result.Meta, tt.meta)
+		}
+	}
+	if got := c.callTool("limited", nil).text(); got != "Error: rate limited" {
+		t.Errorf("retryable error text = %q", got)
+	}
+}
+
+func TestAddTools(t *testing.T) {
+	s := NewServer("test", "1.0")
+	c := newTestClient(t, s)
+
+	var defs []ToolDef
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		defs = append(defs, ToolDef{
+			Name:    name,
+			Handler: textTool(func(context.Context, map[string]interface{}) string { return name }),
+		})
+	}
+
+	// A concurrent list sees either none or all of the batch
+	added := make(chan struct{})
+	go func() {
+		s.AddTools(defs)
+		close(added)
+	}()
+	for done := false; !done; {
+		select {
+		case <-added:
+			done = true
+		default:
+		}
+		var list struct{ Tools []Tool }
+		c.result("tools/list", nil, &list)
+		if n := len(list.Tools); n != 0 && n != len(defs) {
+			t.Fatalf("listed %d tools mid-batch", n)
+		}
+	}
+	for _, def := range defs {
+		if got := c.callTool(def.Name, nil).text(); got != def.Name {
+			t.Errorf("tool %s answered %q", def.Name, got)
+		}
+	}
+
+	s.AddResources([]ResourceDef{
+		{URI: "docs://one", Name: "one", Handler: textResource("1")},
+		{URI: "docs://two", Name: "two", Handler: textResource("2"), Options: []ResourceOption{WithResourceSize(1)}},
+	})
+	resources := c.listResources()
+	if len(resources) != 2 || resources[1].Size != 1 {
+		t.Errorf("resources = %+v", resources)
+	}
+}
+
+func TestAddToolsNotifiesOnce(t *testing.T) {
+	s := NewServer("test", "1.0")
+	c := newTestClient(t, s)
+	waitFor(t, "initialized notification", s.ready.Load)
+
+	// notifications returns the methods of the notifications the server sent
+	// before answering a ping
+	notifications := func() []string {
+		pingID := c.request("ping", nil)
+		var methods []string
+		for {
+			msg := c.receive()
+			if msg.Method == "" && string(msg.ID) == string(pingID) {
+				return methods
+			}
+			methods = append(methods, msg.Method)
+		}
+	}
+
+	var defs []ToolDef
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		defs = append(defs, ToolDef{
+			Name:    name,
+			Handler: textTool(func(context.Context, map[string]interface{}) string { return name }),
+		})
+	}
+	s.AddTools(defs)
+	if got := notifications(); !slices.Equal(got, []string{"notifications/tools/list_changed"}) {
+		t.Errorf("AddTools sent %q, want one tools/list_changed", got)
+	}
+
+	s.AddResources([]ResourceDef{
+		{URI: "docs://one", Name: "one", Handler: textResource("1")},
+		{URI: "docs://two", Name: "two", Handler: textResource("2")},
+	})
+	if got := notifications(); !slices.Equal(got, []string{"notifications/resources/list_changed"}) {
+		t.Errorf("AddResources sent %q, want one resources/list_changed", got)
+	}
+
+	// An empty batch changes nothing
+	s.AddTools(nil)
+	s.AddResources(nil)
+	if got := notifications(); len(got) != 0 {
+		t.Errorf("empty batches sent %q", got)
+	}
+
+	// Before the client is initialized there is no one to notify
+	uninit := NewServer("test", "1.0")
+	c = connect(t, uninit)
+	uninit.AddTools(defs)
+	if got := notifications(); len(got) != 0 {
+		t.Errorf("uninitialized client got %q", got)
+	}
+}
+
+func TestMaxArgumentBytes(t *testing.T) {
+	s := NewServer("test", "1.0")
+	s.AddTool("summarize", "", nil, textTool(func(_ context.Context, args map[string]interface{}) string {
+		return fmt.Sprint(len(args["text"].(string)))
+	}), WithMaxArgumentBytes(64))
+	s.AddTool("unlimited", "", nil, textTool(func(_ context.Context, args map[string]interface{}) string {
+		return fmt.Sprint(len(args["text"].(string)))
+	}))
+	c := newTestClient(t, s)
+
+	if got := c.callTool("summarize", map[string]interface{}{"text": "short"}).text(); got != "5" {
+		t.Errorf("small arguments: got %q", got)
+	}
+
+	big := map[string]interface{}{"name": "summarize", "arguments": map[string]interface{}{"text": strings.Repeat("x", 100)}}
+	if e := c.callError("tools/call", big, -32602); !strings.Contains(e.Message, "limit of 64 bytes") {
+		t.Errorf("oversized arguments: message %q", e.Message)
+	}
+
+	if got := c.callTool("unlimited", map[string]interface{}{"text": strings.Repeat("x", 100)}).text(); got != "100" {
+		t.Errorf("tool without a limit: got %q", got)
+	}
+}
+
+func TestIdempotentToolCache(t *testing.T) {
+	var calls atomic.Int32
+	counter := textTool(func(_ context.Context, args map[string]interface{}) string {
+		return fmt.Sprintf("%v #%d", args["q"], calls.Add(1))
+	})
+
+	s := NewServer("test", "1.0", WithIdempotentToolCache(50*time.Millisecond))
+	s.AddTool("lookup", "", nil, counter, WithToolAnnotations(ToolAnnotations{IdempotentHint: true}))
+	s.AddTool("mutate", "", nil, counter)
+	c := newTestClient(t, s)
+
+	first := c.callTool("lookup", map[string]interface{}{"q": "a", "n": 1})
+	// Key order does not matter
+	second := c.callTool("lookup", json.RawMessage(`{"n": 1, "q": "a"}`))
+	if first.text() != "a #1" || second.text() != first.text() || calls.Load() != 1 {
+		t.Errorf("repeated idempotent call: %q then %q after %d calls", first.text(), second.text(), calls.Load())
+	}
+
+	if got := c.callTool("lookup", map[string]interface{}{"q": "b"}).text(); got != "b #2" {
+		t.Errorf("different arguments: %q", got)
+	}
+
+	// Tools that are not idempotent always run
+	c.callTool("mutate", nil)
+	c.callTool("mutate", nil)
+	if n := calls.Load(); n != 4 {
+		t.Errorf("handler ran %d times, want 4", n)
+	}
+
+	// Entries expire after the TTL
+	time.Sleep(60 * time.Millisecond)
+	if got := c.callTool("lookup", map[string]interface{}{"q": "a", "n": 1}).text(); got != "a #5" {
+		t.Errorf("after the TTL: %q", got)
+	}
+}
+
+func TestRequiredClientCapabilities(t *testing.T) {
+	var calls atomic.Int32
+	newServer := func() *Server {
+		s := NewServer("test", "1.0")
+		s.AddTool("summarize", "", nil, textTool(func(context.Context, map[string]interface{}) string {
+			calls.Add(1)
+			return "summary"
+		}), WithRequiredClientCapabilities("sampling"))
+		return s
+	}
+
+	c := newTestClient(t, newServer())
+	e := c.callError("tools/call", map[string]interface{}{"name": "summarize"}, -32601)
+	if !strings.Contains(e.Message, `"sampling"`) {
+		t.Errorf("error message %q does not name the capability", e.Message)
+	}
+	if calls.Load() != 0 {
+		t.Error("handler ran for a client without sampling")
+	}
+
+	c = connect(t, newServer())
+	c.initializeWith(map[string]interface{}{"sampling": map[string]interface{}{}})
+	c.notify("notifications/initialized", nil)
+	if got := c.callTool("summarize", nil).text(); got != "summary" {
+		t.Errorf("with sampling: %q", got)
+	}
+}
+
+func TestToolsListCache(t *testing.T) {
+	s := NewServer("test", "1.0")
+	s.AddTool("a", "", nil, textTool(func(context.Context, map[string]interface{}) string { return "" }))
+	c := newTestClient(t, s)
+
+	names := func(params interface{}) []string {
+		var result struct {
+			Tools []Tool `json:"tools"`
+		}
+		c.result("tools/list", params, &result)
+		var names []string
+		for _, tool := range result.Tools {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+	cached := func() bool {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return s.toolsList != nil
+	}
+
+	if got := names(nil); !slices.Equal(got, []string{"a"}) {
+		t.Errorf("tools = %q", got)
+	}
+	if !cached() {
+		t.Fatal("tools/list result was not cached")
+	}
+	if got := names(nil); !slices.Equal(got, []string{"a"}) {
+		t.Errorf("cached tools = %q", got)
+	}
+
+	// Registering a tool invalidates the cache
+	s.AddTool("b", "", nil, textTool(func(context.Context, map[string]interface{}) string { return "" }),
+		WithToolAnnotations(ToolAnnotations{ReadOnlyHint: true}))
+	if cached() {
+		t.Error("cache survived AddTool")
+	}
+	if got := names(nil); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("tools after AddTool = %q", got)
+	}
+
+	s.AddTools([]ToolDef{{Name: "c", Handler: textTool(func(context.Context, map[string]interface{}) string { return "" })}})
+	if got := names(nil); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("tools after AddTools = %q", got)
+	}
+
+	// Filtered lists bypass the cache and leave it intact
+	if got := names(map[string]interface{}{"filter": "readOnly"}); !slices.Equal(got, []string{"b"}) {
+		t.Errorf("read-only tools = %q", got)
+	}
+	if got := names(nil); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("tools after a filtered list = %q", got)
+	}
+}
+
+// BenchmarkListTools compares serving tools/list from the cached result
+// with building and marshaling it on every call
+func BenchmarkListTools(b *testing.B) {
+	for _, cached := range []bool{true, false} {
+		name := "cached"
+		if !cached {
+			name = "uncached"
+		}
+		b.Run(name, func(b *testing.B) {
+			s := NewServer("test", "1.0")
+			schema := json.RawMessage(`{"type":"object","properties":{"q":{"type":"string"}}}`)
+			for i := 0; i < 500; i++ {
+				s.AddTool(fmt.Sprintf("tool-%d", i), "A tool for benchmarking", schema,
+					textTool(func(context.Context, map[string]interface{}) string { return "" }))
+			}
+
+			server, client := NewInMemoryTransportPair()
+			if err := s.Connect(context.Background(), server); err != nil {
+				b.Fatal(err)
+			}
+			defer s.Close()
+
+			ctx := context.Background()
+			call := func(id int, method string, params json.RawMessage) {
+				if err := client.Send(ctx, &Message{JSONRPC: "2.0", ID: json.RawMessage(strconv.Itoa(id)), Method: method, Params: params}); err != nil {
+					b.Fatal(err)
+				}
+				resp, err := client.Receive(ctx)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if resp.Error != nil {
+					b.Fatalf("%s: %s", method, resp.Error.Message)
+				}
+			}
+
+			init, err := json.Marshal(initializeParams(ProtocolVersion, map[string]interface{}{}))
+			if err != nil {
+				b.Fatal(err)
+			}
+			call(0, "initialize", init)
+			if err := client.Send(ctx, &Message{JSONRPC: "2.0", Method: "notifications/initialized"}); err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			for i := 1; i <= b.N; i++ {
+				if !cached {
+					s.mu.Lock()
+					s.toolsList = nil
+					s.mu.Unlock()
+				}
+				call(i, "tools/list", nil)
+			}
+		})
+	}
+}
+
+func TestNilToolContent(t *testing.T) {
+	empty := func(context.Context, map[string]interface{}) ([]ToolContent, error) {
+		return nil, nil
+	}
+
+	s := NewServer("test", "1.0")
+	s.AddTool("empty", "", nil, empty)
+	c := newTestClient(t, s)
+
+	resp := c.call("tools/call", map[string]interface{}{"name": "empty"})
+	if resp.Error != nil {
+		t.Fatalf("tools/call: %s", resp.Error.Message)
+	}
+	var result struct {
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	if string(result.Content) != "[]" {
+		t.Errorf("content = %s, want []", result.Content)
+	}
+
+	strict := NewServer("test", "1.0", WithStrictToolResults())
+	strict.AddTool("empty", "", nil, empty)
+	c = newTestClient(t, strict)
+	c.callError("tools/call", map[string]interface{}{"name": "empty"}, -32603)
+}
@@ -2,9 +2,16 @@ package mcp
 
 import (
 	"context"
+	"errors"
 )
 
-// Transport defines the interface for MCP communication channels
+// ErrTransportClosed is returned by transport operations after Close
+var ErrTransportClosed = errors.New("mcp: transport closed")
+
+// Transport defines the interface for MCP communication channels.
+//
+// A transport may also implement a Kind() string method naming the kind of
+// channel it uses, such as "stdio". Handlers can read it with TransportKind.
 type Transport interface {
 	// Send transmits a message through the transport
 	Send(ctx context.Context, msg *Message) error
@@ -15,4 +22,3 @@ type Transport interface {
 	// Close terminates the transport connection
 	Close() error
 }
-
@@ -0,0 +1,122 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrInvalidArguments is wrapped by BindArgs errors for arguments that are
+// missing or of the wrong type. A prompt handler returning such an error is
+// answered with -32602 Invalid params rather than an internal error.
+var ErrInvalidArguments = errors.New("mcp: invalid arguments")
+
+// TypedPrompt registers a prompt whose arguments are described by the struct
+// type T. The declared prompt arguments are derived from T's fields:
+//
+//   - the argument name comes from the field's json tag, or the field name
+//   - an argument is required unless its json tag has omitempty or the field
+//     is a pointer
+//   - the description comes from the field's description tag
+//
+// Incoming arguments are checked and decoded into a T with BindArgs before
+// the handler is called; arguments that do not fit T are rejected with
+// -32602 Invalid params.
+//
+// TypedPrompt panics if T is not a struct type.
+func TypedPrompt[T any](server *Server, name, description string, handler func(ctx context.Context, args T) ([]PromptMessage, error)) {
+	arguments := promptArguments(reflect.TypeOf((*T)(nil)).Elem())
+
+	server.AddPrompt(name, description, arguments, func(ctx context.Context, args map[string]interface{}) ([]PromptMessage, error) {
+		var typed T
+		if err := BindArgs(args, &typed); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, typed)
+	})
+}
+
+// promptArguments derives prompt argument declarations from a struct type
+func promptArguments(t reflect.Type) []PromptArgument {
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("mcp: TypedPrompt requires a struct type, got %s", t))
+	}
+
+	arguments := make([]PromptArgument, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, omitempty, skip := jsonFieldName(field)
+		if skip {
+			continue
+		}
+
+		arguments = append(arguments, PromptArgument{
+			Name:        name,
+			Description: field.Tag.Get("description"),
+			Required:    !omitempty && field.Type.Kind() != reflect.Ptr,
+		})
+	}
+
+	return arguments
+}
+
+// jsonFieldName returns the JSON name of a struct field and whether it is
+// tagged omitempty or skipped entirely
+func jsonFieldName(field reflect.StructField) (name string, omitempty, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+
+	return name, omitempty, false
+}
+
+// BindArgs decodes loosely typed tool or prompt arguments into the value
+// pointed to by v, typically a struct with json tags. When v points to a
+// struct, fields are required unless their json tag has omitempty or they
+// are pointers, following the same rules as TypedPrompt, and a missing
+// required field is an error. Errors caused by the arguments themselves wrap
+// ErrInvalidArguments.
+func BindArgs(args map[string]interface{}, v interface{}) error {
+	if t := reflect.TypeOf(v); t != nil && t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct {
+		for _, arg := range promptArguments(t.Elem()) {
+			if arg.Required && args[arg.Name] == nil {
+				return fmt.Errorf("%w: missing required argument %q", ErrInvalidArguments, arg.Name)
+			}
+		}
+	}
+
+	data, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Errorf("encoding arguments: %w", err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return fmt.Errorf("%w: %w", ErrInvalidArguments, err)
+		}
+		return fmt.Errorf("decoding arguments: %w", err)
+	}
+
+	return nil
+}
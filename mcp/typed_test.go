@@ -0,0 +1,105 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// textMessage builds a prompt message with text content
+func textMessage(role, text string) PromptMessage {
+	content, _ := json.Marshal(TextContent{Type: "text", Text: text})
+	return PromptMessage{Role: role, Content: content}
+}
+
+type reviewArgs struct {
+	Topic string `json:"topic" description:"What to review"`
+	Tone  string `json:"tone,omitempty"`
+	Depth int    `json:"depth,omitempty"`
+}
+
+func TestTypedPrompt(t *testing.T) {
+	s := NewServer("test", "1.0")
+	TypedPrompt(s, "review", "Review something", func(_ context.Context, args reviewArgs) ([]PromptMessage, error) {
+		return []PromptMessage{textMessage(RoleUser, args.Topic+"/"+args.Tone)}, nil
+	})
+	c := newTestClient(t, s)
+
+	var list struct{ Prompts []Prompt }
+	c.result("prompts/list", nil, &list)
+	want := []PromptArgument{
+		{Name: "topic", Description: "What to review", Required: true},
+		{Name: "tone"},
+		{Name: "depth"},
+	}
+	if len(list.Prompts) != 1 || !reflect.DeepEqual(list.Prompts[0].Arguments, want) {
+		t.Fatalf("prompts/list = %+v, want arguments %+v", list.Prompts, want)
+	}
+
+	var got struct{ Messages []PromptMessage }
+	c.result("prompts/get", map[string]interface{}{"name": "review", "arguments": map[string]interface{}{"topic": "code"}}, &got)
+	var content TextContent
+	if len(got.Messages) != 1 || json.Unmarshal(got.Messages[0].Content, &content) != nil || content.Text != "code/" {
+		t.Errorf("prompts/get with only the required argument = %+v", got.Messages)
+	}
+
+	// Bad arguments are the client's fault
+	c.callError("prompts/get", map[string]interface{}{"name": "review", "arguments": map[string]interface{}{"tone": "kind"}}, -32602)
+	c.callError("prompts/get", map[string]interface{}{"name": "review", "arguments": map[string]interface{}{"topic": "code", "depth": "deep"}}, -32602)
+}
+
+func TestTypedPromptRequiresStruct(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("TypedPrompt with a non-struct type did not panic")
+		}
+	}()
+	TypedPrompt(NewServer("test", "1.0"), "bad", "", func(context.Context, string) ([]PromptMessage, error) {
+		return nil, nil
+	})
+}
+
+func TestBindArgs(t *testing.T) {
+	type searchArgs struct {
+		Query string   `json:"query"`
+		Limit int      `json:"limit,omitempty"`
+		Tags  []string `json:"tags"`
+		Exact *bool    `json:"exact"`
+	}
+
+	var args searchArgs
+	err := BindArgs(map[string]interface{}{
+		"query": "go",
+		"limit": json.Number("10"),
+		"tags":  []interface{}{"lang"},
+	}, &args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := searchArgs{Query: "go", Limit: 10, Tags: []string{"lang"}}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("bound %+v, want %+v", args, want)
+	}
+
+	for _, tt := range []struct {
+		name string
+		args map[string]interface{}
+	}{
+		{"missing required", map[string]interface{}{"tags": []interface{}{}}},
+		{"null required", map[string]interface{}{"query": nil, "tags": []interface{}{}}},
+		{"type mismatch", map[string]interface{}{"query": 42, "tags": []interface{}{}}},
+	} {
+		err := BindArgs(tt.args, &searchArgs{})
+		if !errors.Is(err, ErrInvalidArguments) {
+			t.Errorf("%s: got %v, want ErrInvalidArguments", tt.name, err)
+		}
+	}
+
+	// Non-struct targets are decoded as is
+	var m map[string]int
+	if err := BindArgs(map[string]interface{}{"a": 1}, &m); err != nil || m["a"] != 1 {
+		t.Errorf("binding a map: %v, %v", m, err)
+	}
+}